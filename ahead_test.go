@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"testing"
 	"testing/quick"
 )
 
 func TestRadix(t *testing.T) {
 	f := func(in []byte) bool {
-		root := NewNode()
-		insert(&root, in, nil)
+		if len(in) == 0 {
+			return true
+		}
+		root := NewNode[any]()
+		insert(&root, in, nil, 1)
 		out := find(&root, in[:len(in)/2])
 		if len(out) != 1 {
 			fmt.Println(string(in), len(out), "search", string(in[:len(in)/2]))
@@ -30,3 +35,243 @@ func TestRadix(t *testing.T) {
 		log.Fatal(err)
 	}
 }
+
+// words maps random bytes onto a small alphabet so that the generated words
+// share prefixes and split edges often.
+func words(in [][]byte) [][]byte {
+	out := make([][]byte, len(in))
+	for i, b := range in {
+		w := make([]byte, len(b)%6)
+		for j := range w {
+			w[j] = "abc"[b[j]%3]
+		}
+		out[i] = w
+	}
+	return out
+}
+
+func TestTopK(t *testing.T) {
+	f := func(in [][]byte, prefix []byte, k uint8) bool {
+		root := New()
+		freq := make(map[string]int)
+		for _, w := range words(in) {
+			root.Insert(w, nil)
+			if len(w) > 0 {
+				freq[string(w)]++
+			}
+		}
+		p := words([][]byte{prefix})[0]
+
+		var want []Suggestion[any]
+		for w, n := range freq {
+			if strings.HasPrefix(w, string(p)) {
+				want = append(want, Suggestion[any]{Key: []byte(w), Count: n})
+			}
+		}
+		slices.SortFunc(want, func(a, b Suggestion[any]) int {
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+			return bytes.Compare(a.Key, b.Key)
+		})
+		want = want[:min(len(want), int(k%8))]
+
+		got := root.TopK(p, int(k%8))
+		return slices.EqualFunc(got, want, func(a, b Suggestion[any]) bool {
+			return a.Count == b.Count && bytes.Equal(a.Key, b.Key)
+		})
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkNode verifies that the node is a compressed radix tree with consistent
+// counts and cached maximums.
+func checkNode[V any](t *testing.T, n Node[V]) {
+	t.Helper()
+	var best int
+	heads := make(map[string]bool)
+	if (n.children != nil) != (len(n.Edges) >= childIndexMin) {
+		t.Fatalf("node of %d edges has child index %v", len(n.Edges), n.children)
+	}
+	for i, edge := range n.Edges {
+		if len(edge.Key) == 0 {
+			t.Fatal("empty edge key")
+		}
+		if i > 0 && bytes.Compare(n.Edges[i-1].Key, edge.Key) >= 0 {
+			t.Fatalf("edge %q sorts before %q", edge.Key, n.Edges[i-1].Key)
+		}
+		if j := n.index(edge.Key); j != i {
+			t.Fatalf("edge %q is at %d, found at %d", edge.Key, i, j)
+		}
+		if heads[string(head(edge.Key))] {
+			t.Fatalf("edge %q starts with the same character as a sibling", edge.Key)
+		}
+		heads[string(head(edge.Key))] = true
+		if !edge.Endword && len(edge.Node.Edges) < 2 {
+			t.Fatalf("edge %q should have been merged", edge.Key)
+		}
+		if edge.Endword && edge.Frequency() <= 0 {
+			t.Fatalf("edge %q has frequency %d", edge.Key, edge.Frequency())
+		}
+		best = max(best, edge.best())
+		checkNode(t, edge.Node)
+	}
+	if n.Max != best {
+		t.Fatalf("cached max is %d, want %d", n.Max, best)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	f := func(in, del [][]byte, dec []uint8) bool {
+		root := New()
+		freq := make(map[string]int)
+		for _, w := range words(in) {
+			root.Insert(w, nil)
+			if len(w) > 0 {
+				freq[string(w)]++
+			}
+		}
+		for i, w := range words(del) {
+			_, ok := freq[string(w)]
+			var found bool
+			if i < len(dec) && dec[i]%2 == 0 {
+				n := int(dec[i]%4) + 1
+				found = root.Decrement(w, n)
+				if freq[string(w)] -= n; freq[string(w)] <= 0 {
+					delete(freq, string(w))
+				}
+			} else {
+				found = root.Delete(w)
+				delete(freq, string(w))
+			}
+			if found != ok {
+				return false
+			}
+		}
+		checkNode(t, root.Node)
+
+		got := root.TopK(nil, len(freq)+1)
+		if len(got) != len(freq) {
+			return false
+		}
+		for _, s := range got {
+			if freq[string(s.Key)] != s.Count {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	f := func(in, probe [][]byte) bool {
+		root := NewOf[int]()
+		freq := make(map[string]int)
+		value := make(map[string]int)
+		for i, w := range words(in) {
+			root.Insert(w, i)
+			if len(w) > 0 {
+				freq[string(w)]++
+				value[string(w)] = i
+			}
+		}
+		for _, w := range append(words(in), words(probe)...) {
+			v, n, ok := root.Get(w)
+			_, want := freq[string(w)]
+			if ok != want || root.Contains(w) != want {
+				return false
+			}
+			if ok && (v != value[string(w)] || n != freq[string(w)]) {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func levenshtein(s, t []rune) int {
+	row := make([]int, len(t)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := range s {
+		prev := row[0]
+		row[0] = i + 1
+		for j := range t {
+			cost := 1
+			if s[i] == t[j] {
+				cost = 0
+			}
+			prev, row[j+1] = row[j+1], min(row[j+1]+1, row[j]+1, prev+cost)
+		}
+	}
+	return row[len(t)]
+}
+
+func TestFuzzyComplete(t *testing.T) {
+	for name, gen := range map[string]func([][]byte) [][]byte{"bytes": words, "runes": runeWords} {
+		f := func(in [][]byte, query []byte, edits uint8) bool {
+			root := New()
+			freq := make(map[string]int)
+			for _, w := range gen(in) {
+				root.Insert(w, nil)
+				if len(w) > 0 {
+					freq[string(w)]++
+				}
+			}
+			q := []rune(string(gen([][]byte{query})[0]))
+			maxEdits := int(edits % 3)
+
+			want := make(map[string]int)
+			for w := range freq {
+				d, rw := len(q), []rune(w)
+				for i := range len(rw) + 1 {
+					d = min(d, levenshtein(q, rw[:i]))
+				}
+				if d <= maxEdits {
+					want[w] = d
+				}
+			}
+			got := root.FuzzyComplete([]byte(string(q)), maxEdits, len(freq)+1)
+			if len(got) != len(want) {
+				return false
+			}
+			for i, s := range got {
+				if d, ok := want[string(s.Key)]; !ok || d != s.Distance || freq[string(s.Key)] != s.Count {
+					return false
+				}
+				if i > 0 && got[i-1].Distance > s.Distance {
+					return false
+				}
+			}
+			return true
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Fatal(name, err)
+		}
+	}
+
+	root := New()
+	root.Insert([]byte("alexander"), nil)
+	root.Insert([]byte("alexis"), nil)
+	root.Insert([]byte("alexis"), nil)
+	root.Insert([]byte("banana"), nil)
+	got := root.FuzzyComplete([]byte("alxe"), 2, 10)
+	if len(got) != 2 || string(got[0].Key) != "alexis" || string(got[1].Key) != "alexander" {
+		t.Fatalf("unexpected completions %v", got)
+	}
+
+	// An accented letter is a single edit.
+	root.Insert([]byte("cafe"), nil)
+	if got := root.FuzzyComplete([]byte("café"), 1, 10); len(got) != 1 || got[0].Distance != 1 {
+		t.Fatalf("unexpected completions %v", got)
+	}
+}
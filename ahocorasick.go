@@ -0,0 +1,175 @@
+package typeahead
+
+import (
+	"maps"
+	"slices"
+	"sort"
+)
+
+// Match is an occurrence of a term in a text, which spans text[Start:End].
+type Match[V any] struct {
+	Term       []byte
+	Start, End int
+	Value      V
+}
+
+// Matcher finds every occurrence of a set of terms in a text in a single pass,
+// with an Aho-Corasick automaton, e.g. to tag the known entities in free text.
+// It is safe for concurrent use.
+type Matcher[V any] struct {
+	states []acState
+	terms  [][]byte
+	values []V
+}
+
+// acState is a node of the trie of the terms.
+type acState struct {
+	// next holds the transitions, sorted by their byte.
+	next []acEdge
+	// fail is the state of the longest proper suffix of the path of the
+	// state that is also a path in the trie.
+	fail int32
+	// output is the nearest state along the fail links that ends a term, or
+	// -1.
+	output int32
+	// term is the index of the term that ends at the state, or -1.
+	term int32
+}
+
+type acEdge struct {
+	c  byte
+	to int32
+}
+
+// NewMatcher returns a matcher of the given terms and their values. The empty
+// term is ignored.
+func NewMatcher[V any](terms map[string]V) *Matcher[V] {
+	m := &Matcher[V]{states: []acState{{output: -1, term: -1}}}
+	for _, term := range slices.Sorted(maps.Keys(terms)) {
+		m.add([]byte(term), terms[term])
+	}
+	m.build()
+	return m
+}
+
+// Matcher returns a matcher of the words in the tree. The keys are matched as
+// they are stored, so the text must be mapped by the Normalizer of the tree
+// first, if any.
+func (r *Root[V]) Matcher() *Matcher[V] {
+	m := &Matcher[V]{states: []acState{{output: -1, term: -1}}}
+	walk(r.Node.Edges, nil, func(key []byte, edge Edge[V]) bool {
+		if edge.Endword {
+			m.add(key, edge.Value)
+		}
+		return true
+	})
+	m.build()
+	return m
+}
+
+// Matcher returns a matcher of the words in the tree.
+func (t *TernaryTree) Matcher() *Matcher[struct{}] {
+	m := &Matcher[struct{}]{states: []acState{{output: -1, term: -1}}}
+	for _, word := range t.Traverse() {
+		m.add([]byte(word), struct{}{})
+	}
+	m.build()
+	return m
+}
+
+// Len returns the number of terms of the matcher.
+func (m *Matcher[V]) Len() int {
+	return len(m.terms)
+}
+
+// Scan returns every occurrence of the terms in the text, including the ones
+// that overlap. The matches are ordered by their end, and the longest first
+// when they end at the same byte.
+func (m *Matcher[V]) Scan(text []byte) []Match[V] {
+	var out []Match[V]
+	var s int32
+	for i, c := range text {
+		s = m.step(s, c)
+		for o := s; o != -1; o = m.states[o].output {
+			t := m.states[o].term
+			if t == -1 {
+				continue
+			}
+			out = append(out, Match[V]{
+				Term:  m.terms[t],
+				Start: i + 1 - len(m.terms[t]),
+				End:   i + 1,
+				Value: m.values[t],
+			})
+		}
+	}
+	return out
+}
+
+// step follows the transition on c, falling back along the fail links.
+func (m *Matcher[V]) step(s int32, c byte) int32 {
+	for {
+		if to, ok := m.goTo(s, c); ok {
+			return to
+		}
+		if s == 0 {
+			return 0
+		}
+		s = m.states[s].fail
+	}
+}
+
+func (m *Matcher[V]) goTo(s int32, c byte) (int32, bool) {
+	next := m.states[s].next
+	i := sort.Search(len(next), func(i int) bool { return next[i].c >= c })
+	if i < len(next) && next[i].c == c {
+		return next[i].to, true
+	}
+	return 0, false
+}
+
+// add inserts the term into the trie.
+func (m *Matcher[V]) add(term []byte, value V) {
+	if len(term) == 0 {
+		return
+	}
+	var s int32
+	for _, c := range term {
+		to, ok := m.goTo(s, c)
+		if !ok {
+			to = int32(len(m.states))
+			m.states = append(m.states, acState{output: -1, term: -1})
+			next := m.states[s].next
+			i := sort.Search(len(next), func(i int) bool { return next[i].c >= c })
+			m.states[s].next = slices.Insert(next, i, acEdge{c, to})
+		}
+		s = to
+	}
+	if m.states[s].term == -1 {
+		m.states[s].term = int32(len(m.terms))
+		m.terms = append(m.terms, term)
+		m.values = append(m.values, value)
+	}
+}
+
+// build sets the fail and the output links, breadth first so that the links
+// of the shorter paths are set before they are followed.
+func (m *Matcher[V]) build() {
+	queue := []int32{0}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		for _, e := range m.states[s].next {
+			t := &m.states[e.to]
+			if s != 0 {
+				t.fail = m.step(m.states[s].fail, e.c)
+			}
+			if f := m.states[t.fail]; f.term != -1 {
+				t.output = t.fail
+			} else {
+				t.output = f.output
+			}
+			queue = append(queue, e.to)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package typeahead
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestMatcher(t *testing.T) {
+	f := func(in [][]byte, text []byte) bool {
+		terms := make(map[string]int)
+		for i, w := range words(in) {
+			terms[string(w)] = i
+		}
+		text = bytes.Join(words([][]byte{text, text[len(text)/2:], text[len(text)/3:]}), nil)
+
+		var want []Match[int]
+		for end := 1; end <= len(text); end++ {
+			for start := range end {
+				if v, ok := terms[string(text[start:end])]; ok {
+					want = append(want, Match[int]{Term: text[start:end], Start: start, End: end, Value: v})
+				}
+			}
+		}
+
+		root := NewOf[int]()
+		for term, v := range terms {
+			root.Insert([]byte(term), v)
+		}
+		for _, m := range []*Matcher[int]{NewMatcher(terms), root.Matcher()} {
+			got := m.Scan(text)
+			if !slices.EqualFunc(got, want, func(a, b Match[int]) bool {
+				return bytes.Equal(a.Term, b.Term) && a.Start == b.Start && a.End == b.End && a.Value == b.Value
+			}) {
+				t.Logf("%q: got %v, want %v", text, got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMatcherTernaryTree(t *testing.T) {
+	tree := NewTernaryTree()
+	for _, w := range []string{"he", "she", "his", "hers"} {
+		tree.Insert(w)
+	}
+	m := tree.Matcher()
+	if m.Len() != 4 {
+		t.Fatalf("got %d terms, want 4", m.Len())
+	}
+	got := m.Scan([]byte("ushers"))
+	slices.SortFunc(got, func(a, b Match[struct{}]) int { return cmp.Compare(a.Start, b.Start) })
+	want := []struct {
+		term       string
+		start, end int
+	}{{"she", 1, 4}, {"he", 2, 4}, {"hers", 2, 6}}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i, w := range want {
+		if string(got[i].Term) != w.term || got[i].Start != w.start || got[i].End != w.end {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,467 @@
+package typeahead
+
+import (
+	"bytes"
+	"sort"
+)
+
+// ART is an adaptive radix tree, another backend next to Root for large
+// vocabularies. The inner nodes come in four sizes, of up to 4, 16, 48 and
+// 256 children, and grow as children are added, so that the sparse nodes stay
+// small and the dense ones are indexed by the next byte of the key. The nodes
+// are linked by pointers and never copied.
+//
+// The paths of the inner nodes with a single child are compressed into the
+// prefix of the next node, and a subtree that holds a single key is a leaf
+// until a second key is added (lazy expansion), so most keys take a single
+// allocation.
+//
+// Unlike Root, the keys are stored as they are given, and the words are not
+// ranked.
+//
+// See https://db.in.tum.de/~leis/papers/ART.pdf
+type ART[V any] struct {
+	root artNode[V]
+	size int
+}
+
+// NewART returns an empty adaptive radix tree that stores values of type V.
+func NewART[V any]() *ART[V] {
+	return &ART[V]{}
+}
+
+// artLeaf holds a key, with its value and frequency.
+type artLeaf[V any] struct {
+	key   []byte
+	value V
+	count int
+}
+
+// artHeader is the part that the inner nodes of every size share.
+type artHeader[V any] struct {
+	// prefix is the compressed path, which the keys below the node share
+	// after the byte that leads to the node.
+	prefix []byte
+	// leaf holds the key that ends at the node, which is a prefix of the
+	// keys of the children.
+	leaf *artLeaf[V]
+	// n is the number of children.
+	n int
+}
+
+// artNode is either an *artLeaf or an inner node, see artInner.
+type artNode[V any] interface {
+	// header returns the header of an inner node, or nil for a leaf.
+	header() *artHeader[V]
+}
+
+// artInner is an inner node of one of the four sizes.
+type artInner[V any] interface {
+	artNode[V]
+	// child returns the slot of the child at the byte c, or nil.
+	child(c byte) *artNode[V]
+	// add adds a child at the byte c, which must not have one yet. It
+	// returns the node that replaces the node, which is grown when it was
+	// full.
+	add(c byte, child artNode[V]) artInner[V]
+	// each calls fn for the children in the order of their bytes, until fn
+	// returns false.
+	each(fn func(child artNode[V]) bool) bool
+}
+
+// Insert adds the key with the given value, raising its frequency by one.
+// The empty key is ignored.
+func (t *ART[V]) Insert(key []byte, value V) {
+	if len(key) == 0 {
+		return
+	}
+	if t.insert(&t.root, key, 0, value) {
+		t.size++
+	}
+}
+
+// insert adds the key below the node in the slot, whose path matches the
+// first depth bytes of the key. It reports whether the key is new.
+func (t *ART[V]) insert(slot *artNode[V], key []byte, depth int, value V) bool {
+	switch n := (*slot).(type) {
+	case nil:
+		*slot = newARTLeaf(key, value)
+		return true
+	case *artLeaf[V]:
+		if bytes.Equal(n.key, key) {
+			n.value = value
+			n.count++
+			return false
+		}
+		// Expand the leaf into a node that holds both keys, below their
+		// common prefix.
+		p := depth + commonPrefix(n.key[depth:], key[depth:])
+		in := &art4[V]{}
+		in.prefix = n.key[depth:p]
+		var inner artInner[V] = in
+		inner = placeLeaf(inner, n, p)
+		*slot = placeLeaf(inner, newARTLeaf(key, value), p)
+		return true
+	}
+
+	in := (*slot).(artInner[V])
+	h := in.header()
+	p := commonPrefix(h.prefix, key[depth:])
+	if p < len(h.prefix) {
+		// The key leaves the compressed path, so split the path at that
+		// byte with a new node.
+		parent := &art4[V]{}
+		parent.prefix = h.prefix[:p]
+		c := h.prefix[p]
+		h.prefix = h.prefix[p+1:]
+		var inner artInner[V] = parent
+		inner = inner.add(c, in)
+		*slot = placeLeaf(inner, newARTLeaf(key, value), depth+p)
+		return true
+	}
+	depth += p
+	if depth == len(key) {
+		if h.leaf == nil {
+			h.leaf = newARTLeaf(key, value)
+			return true
+		}
+		h.leaf.value = value
+		h.leaf.count++
+		return false
+	}
+	if next := in.child(key[depth]); next != nil {
+		return t.insert(next, key, depth+1, value)
+	}
+	*slot = in.add(key[depth], newARTLeaf(key, value))
+	return true
+}
+
+// placeLeaf adds the leaf below the node, whose path ends at depth.
+func placeLeaf[V any](in artInner[V], leaf *artLeaf[V], depth int) artInner[V] {
+	if len(leaf.key) == depth {
+		in.header().leaf = leaf
+		return in
+	}
+	return in.add(leaf.key[depth], leaf)
+}
+
+func newARTLeaf[V any](key []byte, value V) *artLeaf[V] {
+	return &artLeaf[V]{key: bytes.Clone(key), value: value, count: 1}
+}
+
+// commonPrefix returns the length of the common prefix of s and t.
+func commonPrefix(s, t []byte) int {
+	n := min(len(s), len(t))
+	for i := range n {
+		if s[i] != t[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// Get returns the value and the frequency stored for the exact key.
+func (t *ART[V]) Get(key []byte) (value V, count int, ok bool) {
+	if l := t.lookup(key); l != nil {
+		return l.value, l.count, true
+	}
+	return value, 0, false
+}
+
+// Contains reports whether the exact key is stored in the tree.
+func (t *ART[V]) Contains(key []byte) bool {
+	return t.lookup(key) != nil
+}
+
+// Len returns the number of distinct keys in the tree.
+func (t *ART[V]) Len() int {
+	return t.size
+}
+
+func (t *ART[V]) lookup(key []byte) *artLeaf[V] {
+	if len(key) == 0 {
+		return nil
+	}
+	node, depth := t.root, 0
+	for node != nil {
+		in, ok := node.(artInner[V])
+		if !ok {
+			// The path to a leaf is not checked on the way down, so the
+			// whole key is compared here.
+			if l := node.(*artLeaf[V]); bytes.Equal(l.key, key) {
+				return l
+			}
+			return nil
+		}
+		h := in.header()
+		if !bytes.HasPrefix(key[depth:], h.prefix) {
+			return nil
+		}
+		depth += len(h.prefix)
+		if depth == len(key) {
+			return h.leaf
+		}
+		next := in.child(key[depth])
+		if next == nil {
+			return nil
+		}
+		node, depth = *next, depth+1
+	}
+	return nil
+}
+
+// FindRecursive returns the keys of all the words that complete the given
+// prefix, in lexicographic order.
+func (t *ART[V]) FindRecursive(prefix []byte) [][]byte {
+	var out [][]byte
+	t.complete(prefix, func(l *artLeaf[V]) bool {
+		if len(l.key) != len(prefix) {
+			out = append(out, l.key)
+		}
+		return true
+	})
+	return out
+}
+
+// complete calls fn for the leaves whose keys start with the prefix, in
+// lexicographic order, until fn returns false.
+func (t *ART[V]) complete(prefix []byte, fn func(l *artLeaf[V]) bool) {
+	node, depth := t.root, 0
+	for node != nil {
+		in, ok := node.(artInner[V])
+		if !ok {
+			if l := node.(*artLeaf[V]); bytes.HasPrefix(l.key, prefix) {
+				fn(l)
+			}
+			return
+		}
+		h := in.header()
+		rest := prefix[depth:]
+		if len(rest) <= len(h.prefix) {
+			// The prefix ends within the path, so every key below
+			// completes it.
+			if bytes.HasPrefix(h.prefix, rest) {
+				walkART(node, fn)
+			}
+			return
+		}
+		if !bytes.HasPrefix(rest, h.prefix) {
+			return
+		}
+		depth += len(h.prefix)
+		next := in.child(prefix[depth])
+		if next == nil {
+			return
+		}
+		node, depth = *next, depth+1
+	}
+}
+
+// walkART visits the leaves below the node in lexicographic order. The key
+// that ends at a node is a prefix of the others, so it comes first.
+func walkART[V any](node artNode[V], fn func(l *artLeaf[V]) bool) bool {
+	in, ok := node.(artInner[V])
+	if !ok {
+		return fn(node.(*artLeaf[V]))
+	}
+	if l := in.header().leaf; l != nil && !fn(l) {
+		return false
+	}
+	return in.each(func(child artNode[V]) bool {
+		return walkART(child, fn)
+	})
+}
+
+// Autocompleter returns the tree as an Autocompleter. The keys inserted
+// through it are stored with the zero value.
+func (t *ART[V]) Autocompleter() Autocompleter {
+	return artCompleter[V]{t}
+}
+
+// artCompleter adapts the adaptive radix tree to the Autocompleter
+// interface.
+type artCompleter[V any] struct {
+	*ART[V]
+}
+
+func (c artCompleter[V]) Insert(key string) {
+	var zero V
+	c.ART.Insert([]byte(key), zero)
+}
+
+func (c artCompleter[V]) Contains(key string) bool {
+	return c.ART.Contains([]byte(key))
+}
+
+func (c artCompleter[V]) Complete(prefix string, limit int) []string {
+	var out []string
+	c.complete([]byte(prefix), func(l *artLeaf[V]) bool {
+		out = append(out, string(l.key))
+		return limit <= 0 || len(out) < limit
+	})
+	return out
+}
+
+func (l *artLeaf[V]) header() *artHeader[V] { return nil }
+
+// art4 is the smallest inner node, whose keys are searched linearly.
+type art4[V any] struct {
+	artHeader[V]
+	keys     [4]byte
+	children [4]artNode[V]
+}
+
+func (n *art4[V]) header() *artHeader[V] { return &n.artHeader }
+
+func (n *art4[V]) child(c byte) *artNode[V] {
+	for i := range n.n {
+		if n.keys[i] == c {
+			return &n.children[i]
+		}
+	}
+	return nil
+}
+
+func (n *art4[V]) add(c byte, child artNode[V]) artInner[V] {
+	if n.n == len(n.keys) {
+		g := &art16[V]{artHeader: n.artHeader}
+		copy(g.keys[:], n.keys[:])
+		copy(g.children[:], n.children[:])
+		return g.add(c, child)
+	}
+	i := 0
+	for i < n.n && n.keys[i] < c {
+		i++
+	}
+	copy(n.keys[i+1:n.n+1], n.keys[i:n.n])
+	copy(n.children[i+1:n.n+1], n.children[i:n.n])
+	n.keys[i], n.children[i] = c, child
+	n.n++
+	return n
+}
+
+func (n *art4[V]) each(fn func(child artNode[V]) bool) bool {
+	for _, child := range n.children[:n.n] {
+		if !fn(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// art16 is the inner node whose sorted keys are binary searched.
+type art16[V any] struct {
+	artHeader[V]
+	keys     [16]byte
+	children [16]artNode[V]
+}
+
+func (n *art16[V]) header() *artHeader[V] { return &n.artHeader }
+
+func (n *art16[V]) child(c byte) *artNode[V] {
+	i := sort.Search(n.n, func(i int) bool { return n.keys[i] >= c })
+	if i < n.n && n.keys[i] == c {
+		return &n.children[i]
+	}
+	return nil
+}
+
+func (n *art16[V]) add(c byte, child artNode[V]) artInner[V] {
+	if n.n == len(n.keys) {
+		g := &art48[V]{artHeader: n.artHeader}
+		for i, k := range n.keys {
+			g.index[k] = uint8(i + 1)
+			g.children[i] = n.children[i]
+		}
+		return g.add(c, child)
+	}
+	i := sort.Search(n.n, func(i int) bool { return n.keys[i] >= c })
+	copy(n.keys[i+1:n.n+1], n.keys[i:n.n])
+	copy(n.children[i+1:n.n+1], n.children[i:n.n])
+	n.keys[i], n.children[i] = c, child
+	n.n++
+	return n
+}
+
+func (n *art16[V]) each(fn func(child artNode[V]) bool) bool {
+	for _, child := range n.children[:n.n] {
+		if !fn(child) {
+			return false
+		}
+	}
+	return true
+}
+
+// art48 is the inner node that maps every byte to the slot of its child, if
+// any, so that 48 pointers are kept instead of 256.
+type art48[V any] struct {
+	artHeader[V]
+	// index holds the slot of the child at every byte plus one, or zero.
+	index    [256]uint8
+	children [48]artNode[V]
+}
+
+func (n *art48[V]) header() *artHeader[V] { return &n.artHeader }
+
+func (n *art48[V]) child(c byte) *artNode[V] {
+	if i := n.index[c]; i != 0 {
+		return &n.children[i-1]
+	}
+	return nil
+}
+
+func (n *art48[V]) add(c byte, child artNode[V]) artInner[V] {
+	if n.n == len(n.children) {
+		g := &art256[V]{artHeader: n.artHeader}
+		for k, i := range n.index {
+			if i != 0 {
+				g.children[k] = n.children[i-1]
+			}
+		}
+		return g.add(c, child)
+	}
+	// The children are never removed, so the slots are filled in order.
+	n.children[n.n] = child
+	n.index[c] = uint8(n.n + 1)
+	n.n++
+	return n
+}
+
+func (n *art48[V]) each(fn func(child artNode[V]) bool) bool {
+	for _, i := range n.index {
+		if i != 0 && !fn(n.children[i-1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// art256 is the largest inner node, which holds a child for every byte.
+type art256[V any] struct {
+	artHeader[V]
+	children [256]artNode[V]
+}
+
+func (n *art256[V]) header() *artHeader[V] { return &n.artHeader }
+
+func (n *art256[V]) child(c byte) *artNode[V] {
+	if n.children[c] != nil {
+		return &n.children[c]
+	}
+	return nil
+}
+
+func (n *art256[V]) add(c byte, child artNode[V]) artInner[V] {
+	n.children[c] = child
+	n.n++
+	return n
+}
+
+func (n *art256[V]) each(fn func(child artNode[V]) bool) bool {
+	for _, child := range n.children {
+		if child != nil && !fn(child) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package typeahead
+
+import (
+	"bytes"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestART(t *testing.T) {
+	f := func(in [][]byte, prefix []byte) bool {
+		tree := NewART[int]()
+		freq := make(map[string]int)
+		last := make(map[string]int)
+		// Mix the words of a small alphabet, which share long prefixes, with
+		// short random bytes, which fill the nodes of every size.
+		keys := append(words(in), runeWords(in)...)
+		for _, b := range in {
+			keys = append(keys, b[:len(b)%4])
+		}
+		for i, w := range keys {
+			tree.Insert(w, i)
+			if len(w) > 0 {
+				freq[string(w)]++
+				last[string(w)] = i
+			}
+		}
+		if tree.Len() != len(freq) {
+			t.Logf("got %d keys, want %d", tree.Len(), len(freq))
+			return false
+		}
+		for _, k := range slices.Sorted(maps.Keys(freq)) {
+			if v, n, ok := tree.Get([]byte(k)); !ok || n != freq[k] || v != last[k] {
+				t.Logf("get %q: got %d %d %t", k, v, n, ok)
+				return false
+			}
+			if tree.Contains(append([]byte(k), 0)) != (freq[k+"\x00"] > 0) {
+				return false
+			}
+		}
+
+		for _, p := range [][]byte{nil, prefix[:len(prefix)%3], words([][]byte{prefix})[0]} {
+			var want []string
+			for _, k := range slices.Sorted(maps.Keys(freq)) {
+				if bytes.HasPrefix([]byte(k), p) && k != string(p) {
+					want = append(want, k)
+				}
+			}
+			got := tree.FindRecursive(p)
+			if !slices.EqualFunc(got, want, func(a []byte, b string) bool { return string(a) == b }) {
+				t.Logf("prefix %q: got %q, want %q", p, got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 500}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestARTGrow(t *testing.T) {
+	tree := NewART[int]()
+	for c := range 256 {
+		tree.Insert([]byte{'x', byte(255 - c)}, c)
+		var want artNode[int]
+		switch n := c + 1; {
+		case n == 1:
+			want = &artLeaf[int]{}
+		case n <= 4:
+			want = &art4[int]{}
+		case n <= 16:
+			want = &art16[int]{}
+		case n <= 48:
+			want = &art48[int]{}
+		default:
+			want = &art256[int]{}
+		}
+		if reflect.TypeOf(tree.root) != reflect.TypeOf(want) {
+			t.Fatalf("%d children: got %T, want %T", c+1, tree.root, want)
+		}
+	}
+	tree.Insert([]byte("x"), -1)
+	got := tree.FindRecursive([]byte("x"))
+	if len(got) != 256 || got[0][1] != 0 || got[255][1] != 255 {
+		t.Fatalf("got %d keys", len(got))
+	}
+	if v, n, ok := tree.Get([]byte("x")); !ok || n != 1 || v != -1 {
+		t.Fatalf("got %d %d %t", v, n, ok)
+	}
+}
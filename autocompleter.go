@@ -0,0 +1,57 @@
+package typeahead
+
+// Autocompleter is the common interface of the tree implementations in this
+// package, so that they can be swapped and benchmarked against each other.
+type Autocompleter interface {
+	// Insert adds the key.
+	Insert(key string)
+	// Contains reports whether the exact key was inserted.
+	Contains(key string) bool
+	// Complete returns up to limit keys that start with the prefix,
+	// including the prefix itself. A limit of zero or less returns every
+	// completion.
+	Complete(prefix string, limit int) []string
+	// Len returns the number of distinct keys.
+	Len() int
+}
+
+var (
+	_ Autocompleter = completer[any]{}
+	_ Autocompleter = (*TrieNode)(nil)
+	_ Autocompleter = (*Trie)(nil)
+	_ Autocompleter = (*TernaryTree)(nil)
+	_ Autocompleter = artCompleter[any]{}
+)
+
+// Autocompleter returns the tree as an Autocompleter. The keys inserted
+// through it are stored with the zero value.
+func (r *Root[V]) Autocompleter() Autocompleter {
+	return completer[V]{r}
+}
+
+// completer adapts the radix tree, whose methods take byte slices and values,
+// to the Autocompleter interface.
+type completer[V any] struct {
+	*Root[V]
+}
+
+func (c completer[V]) Insert(key string) {
+	var zero V
+	c.Root.Insert([]byte(key), zero)
+}
+
+func (c completer[V]) Contains(key string) bool {
+	return c.Root.Contains([]byte(key))
+}
+
+func (c completer[V]) Complete(prefix string, limit int) []string {
+	var out []string
+	path, edges := seek(&(c.Node), c.normalizeQuery([]byte(prefix)))
+	walk(edges, path, func(key []byte, edge Edge[V]) bool {
+		if edge.Endword {
+			out = append(out, string(key))
+		}
+		return limit <= 0 || len(out) < limit
+	})
+	return out
+}
@@ -0,0 +1,83 @@
+package typeahead
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func backends() map[string]func() Autocompleter {
+	return map[string]func() Autocompleter{
+		"radix":    func() Autocompleter { return New().Autocompleter() },
+		"trienode": func() Autocompleter { return NewTrieNode("^") },
+		"trie":     func() Autocompleter { return NewTrie("") },
+		"ternary":  func() Autocompleter { return NewTernaryTree() },
+		"art":      func() Autocompleter { return NewART[any]().Autocompleter() },
+	}
+}
+
+func TestAutocompleter(t *testing.T) {
+	for name, newBackend := range backends() {
+		t.Run(name, func(t *testing.T) {
+			f := func(in [][]byte, prefix []byte) bool {
+				ac := newBackend()
+				seen := make(map[string]bool)
+				for _, w := range words(in) {
+					ac.Insert(string(w))
+					if len(w) > 0 {
+						seen[string(w)] = true
+					}
+				}
+				p := string(words([][]byte{prefix})[0])
+
+				var want []string
+				for w := range seen {
+					if !ac.Contains(w) {
+						return false
+					}
+					if strings.HasPrefix(w, p) {
+						want = append(want, w)
+					}
+				}
+				got := ac.Complete(p, 0)
+				slices.Sort(got)
+				slices.Sort(want)
+				if !slices.Equal(got, want) || ac.Len() != len(seen) || ac.Contains(p+"x") {
+					return false
+				}
+				return len(want) == 0 || len(ac.Complete(p, 1)) == 1
+			}
+			if err := quick.Check(f, nil); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func BenchmarkAutocompleter(b *testing.B) {
+	keys := make([]string, 10000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("%08x", i*7919)
+	}
+	for name, newBackend := range backends() {
+		b.Run(name+"/insert", func(b *testing.B) {
+			for b.Loop() {
+				ac := newBackend()
+				for _, k := range keys {
+					ac.Insert(k)
+				}
+			}
+		})
+		ac := newBackend()
+		for _, k := range keys {
+			ac.Insert(k)
+		}
+		b.Run(name+"/complete", func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				ac.Complete(keys[i%len(keys)][:2], 10)
+			}
+		})
+	}
+}
@@ -19,8 +19,10 @@ const TrieBase = 2
 //         fmt.Println("has cac", trieContains(t, "car"))
 // }
 
+// GetBit returns the nth bit of the key, counting from the most significant
+// bit of the first byte. The key is padded with zero bits.
 func GetBit(key string, n int) int {
-	if len(key) == n/BitsPerByte {
+	if n/BitsPerByte >= len(key) {
 		return 0
 	}
 	if key[n/BitsPerByte]&(0x1<<uint(BitsPerByte-1-n%BitsPerByte)) != 0 {
@@ -29,6 +31,21 @@ func GetBit(key string, n int) int {
 	return 0
 }
 
+// keyBit returns the nth bit of the key as the trie branches on it. Every byte
+// is preceded by a one bit, and the key is padded with zero bits, so that
+// unlike with GetBit no key is a prefix of another, e.g. "a" of "a\x00". The
+// keys still sort in lexicographic order.
+func keyBit(key string, n int) int {
+	i, j := n/(BitsPerByte+1), n%(BitsPerByte+1)
+	if i >= len(key) {
+		return 0
+	}
+	if j == 0 {
+		return 1
+	}
+	return GetBit(key[i:i+1], j-1)
+}
+
 type Trie struct {
 	key      string
 	children [TrieBase]*Trie
@@ -59,7 +76,7 @@ func TrieInsert(trie *Trie, key string) *Trie {
 
 	// Search for the key.
 	for t = trie; !isLeaf(t); bit, t = bit+1, kid {
-		bitvalue = GetBit(key, bit)
+		bitvalue = keyBit(key, bit)
 		kid = t.children[bitvalue]
 		if kid == nil {
 			t.children[bitvalue] = NewTrie(key)
@@ -79,13 +96,13 @@ func TrieInsert(trie *Trie, key string) *Trie {
 
 	// Walk the common prefix.
 	// bitvalue = GetBit(key, bit)
-	bitvalue = GetBit(key, bit)
-	for GetBit(oldKey, bit) == bitvalue {
+	bitvalue = keyBit(key, bit)
+	for keyBit(oldKey, bit) == bitvalue {
 		kid = NewTrie("")
 		t.children[bitvalue] = kid
 		bit++
 		t = kid
-		bitvalue = GetBit(key, bit)
+		bitvalue = keyBit(key, bit)
 	}
 	// Then split.
 	t.children[bitvalue] = NewTrie(key)
@@ -97,10 +114,75 @@ func TrieInsert(trie *Trie, key string) *Trie {
 
 func TrieContains(trie *Trie, target string) bool {
 	for bit := 0; trie != nil && !isLeaf(trie); bit++ {
-		trie = trie.children[GetBit(target, bit)]
+		trie = trie.children[keyBit(target, bit)]
 	}
 	if trie == nil {
 		return false
 	}
 	return strings.EqualFold(trie.key, target)
 }
+
+// Insert adds the key to the trie, see TrieInsert. The trie must be created
+// with NewTrie, as a nil trie cannot grow in place.
+func (t *Trie) Insert(key string) {
+	if key == "" {
+		return
+	}
+	TrieInsert(t, key)
+}
+
+// Contains reports whether the exact key was inserted. Unlike TrieContains,
+// the keys are compared case sensitively, like the other Autocompleter
+// implementations.
+func (t *Trie) Contains(key string) bool {
+	if key == "" {
+		return false
+	}
+	node := t
+	for bit := 0; node != nil && !isLeaf(node); bit++ {
+		node = node.children[keyBit(key, bit)]
+	}
+	return node != nil && node.key == key
+}
+
+// Complete returns up to limit keys that start with the prefix. A limit of
+// zero or less returns every completion. Since the keys are stored in the
+// leaves in bit order, the completions are sorted.
+func (t *Trie) Complete(prefix string, limit int) []string {
+	node := t
+	for bit := 0; !isLeaf(node) && bit < len(prefix)*(BitsPerByte+1); bit++ {
+		node = node.children[keyBit(prefix, bit)]
+	}
+	var out []string
+	node.collect(prefix, limit, &out)
+	return out
+}
+
+// Len returns the number of keys in the trie.
+func (t *Trie) Len() int {
+	if t == nil {
+		return 0
+	}
+	if isLeaf(t) {
+		if t.key == "" {
+			return 0
+		}
+		return 1
+	}
+	return t.children[0].Len() + t.children[1].Len()
+}
+
+// collect appends the keys of the leaves that start with the prefix to out,
+// and reports whether the limit has not been reached yet.
+func (t *Trie) collect(prefix string, limit int, out *[]string) bool {
+	if t == nil {
+		return true
+	}
+	if isLeaf(t) {
+		if t.key != "" && strings.HasPrefix(t.key, prefix) {
+			*out = append(*out, t.key)
+		}
+		return limit <= 0 || len(*out) < limit
+	}
+	return t.children[0].collect(prefix, limit, out) && t.children[1].collect(prefix, limit, out)
+}
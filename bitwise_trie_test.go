@@ -0,0 +1,58 @@
+package typeahead
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestTrieBytes(t *testing.T) {
+	f := func(in [][]byte, prefix []byte) bool {
+		trie := NewTrie("")
+		seen := make(map[string]bool)
+		for _, b := range in {
+			// Keep the keys short, so that they share their prefixes.
+			w := string(b[:len(b)%4])
+			trie.Insert(w)
+			if w != "" {
+				seen[w] = true
+			}
+		}
+		for _, b := range in {
+			w := string(b[:len(b)%4])
+			if trie.Contains(w) != seen[w] || trie.Contains(w+"\x00") != seen[w+"\x00"] {
+				t.Logf("contains %q: got %t", w, !seen[w])
+				return false
+			}
+		}
+		p := string(prefix[:len(prefix)%2])
+		var want []string
+		for w := range seen {
+			if strings.HasPrefix(w, p) {
+				want = append(want, w)
+			}
+		}
+		slices.Sort(want)
+		got := trie.Complete(p, 0)
+		if !slices.Equal(got, want) || trie.Len() != len(seen) {
+			t.Logf("prefix %q: got %q, want %q", p, got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	trie := NewTrie("")
+	trie.Insert("a")
+	trie.Insert("a\x00")
+	trie.Insert("apple")
+	if trie.Contains("APPLE") || !trie.Contains("apple") || trie.Contains("a\x00\x00") || trie.Len() != 3 {
+		t.Fatal("got a match that differs from the inserted keys")
+	}
+	if got := trie.Complete("a", 0); !slices.Equal(got, []string{"a", "a\x00", "apple"}) {
+		t.Fatalf("got %q", got)
+	}
+}
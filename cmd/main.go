@@ -3,14 +3,18 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"encoding/gob"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
 	"runtime/pprof"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/alextanhongpin/typeahead"
@@ -24,7 +28,11 @@ func main() {
 		source      = flag.String("source", "", "the default dictionary to load")
 		in          = flag.String("in", "", "the file that stores the struct")
 		out         = flag.String("out", "", "the destination to store the file to")
+		index       = flag.String("index", "", "the destination to compile the read-only index to")
+		ingest      = flag.String("ingest", "", "the query log to follow for new counts from its end, e.g. queries.log")
+		format      typeahead.Format
 	)
+	flag.TextVar(&format, "format", typeahead.Text, "the format of the query log: text, tsv or jsonl")
 	flag.Parse()
 	if *cpuprofile != "" {
 		cpufile, err := os.Create(*cpuprofile)
@@ -40,23 +48,18 @@ func main() {
 	radix := typeahead.NewTrieNode("^")
 
 	if *in != "" {
-		var f *os.File
-		_, err := os.Stat(*in)
-		if os.IsNotExist(err) {
-			f, err = os.Create(*in)
-		} else {
-			f, err = os.Open(*in)
-		}
-		if err != nil {
-			log.Fatal(err)
-		}
-		dec := gob.NewDecoder(f)
-		err = dec.Decode(root)
-		if err != nil {
+		r, err := typeahead.Load[any](*in)
+		switch {
+		case errors.Is(err, typeahead.ErrNotFound):
+			log.Println(*in, "does not exist, starting with an empty tree")
+		case err != nil:
 			log.Fatal(err)
+		default:
+			root = r
+			log.Println("read from", *in)
 		}
-		log.Println("read from", *in)
 	}
+	root.Normalizer = typeahead.Standard
 
 	if *source != "" {
 		f, err := os.Open(*source)
@@ -69,7 +72,7 @@ func main() {
 		scanner := bufio.NewScanner(f)
 		var count, words int
 		for scanner.Scan() {
-			b := bytes.ToLower(scanner.Bytes())
+			b := scanner.Bytes()
 			words++
 			count += len(b)
 			root.Insert(b, nil)
@@ -87,22 +90,24 @@ func main() {
 	}
 
 	if *out != "" {
-		var f *os.File
-		_, err := os.Stat(*out)
-		if os.IsNotExist(err) {
-			f, err = os.Create(*out)
-		} else {
-			f, err = os.Open(*out)
+		if err := typeahead.Save(*out, root); err != nil {
+			log.Fatal(err)
 		}
+		log.Println("store to", *out)
+	}
+
+	if *index != "" {
+		f, err := os.Create(*index)
 		if err != nil {
 			log.Fatal(err)
 		}
-		enc := gob.NewEncoder(f)
-		err = enc.Encode(root)
-		if err != nil {
+		if _, err := root.WriteIndex(f); err != nil {
 			log.Fatal(err)
 		}
-		log.Println("store to", *out)
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("compiled index to", *index)
 	}
 
 	if *memprofile != "" {
@@ -116,6 +121,22 @@ func main() {
 		defer memfile.Close()
 	}
 
+	// The query log is followed in the background, so the reads go through a
+	// concurrent tree from here on.
+	tree := typeahead.NewConcurrent[any]()
+	tree.Store(root)
+	if *ingest != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if !*interactive {
+			// Without the prompt, the log is followed until the program is
+			// stopped.
+			tail(ctx, *ingest, format, tree)
+			return
+		}
+		go tail(ctx, *ingest, format, tree)
+	}
+
 	if *interactive {
 		fmt.Println("Enter a search keyword:")
 		reader := bufio.NewScanner(os.Stdin)
@@ -127,7 +148,7 @@ func main() {
 			fmt.Printf("searching for %s:\n", b)
 			start := time.Now()
 			// result := root.Find(b)
-			result := root.FindRecursive(b)
+			result := tree.FindRecursive(b)
 			var count int
 			fmt.Printf("found %d results in %s\n", len(result), time.Since(start))
 			// for r, _ := range result {
@@ -152,3 +173,91 @@ func main() {
 		}
 	}
 }
+
+// tail ingests the lines that are appended to the query log until ctx is
+// done. It starts at the end of the log, so that the queries that were logged
+// before are not counted again on every restart, and a line is only ingested
+// once it is complete. A log that is truncated is read again from its start,
+// and a log that is rotated is followed under its path.
+func tail(ctx context.Context, path string, format typeahead.Format, tree *typeahead.Concurrent[any]) {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() { f.Close() }()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var (
+		batch   bytes.Buffer
+		partial []byte
+		// rotated is the new log, which is switched to once the old one
+		// is read to its end.
+		rotated *os.File
+	)
+	restart := func(at *os.File) {
+		f, offset, partial = at, 0, partial[:0]
+	}
+	r := bufio.NewReader(f)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		line, err := r.ReadBytes('\n')
+		offset += int64(len(line))
+		// Keep the incomplete line until the rest of it is written.
+		partial = append(partial, line...)
+		if err == nil {
+			batch.Write(partial)
+			partial = partial[:0]
+			continue
+		}
+		if err != io.EOF {
+			log.Fatal(err)
+		}
+		if batch.Len() > 0 {
+			n, err := tree.Ingest(&batch, format)
+			if err != nil {
+				log.Println(err)
+			}
+			log.Println("ingested", n, "lines from", path)
+			batch.Reset()
+		}
+		if rotated != nil {
+			f.Close()
+			restart(rotated)
+			rotated = nil
+			r.Reset(f)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := f.Stat()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if latest, err := os.Stat(path); err == nil && !os.SameFile(cur, latest) {
+			// Read what is left of the old log before switching.
+			if rotated, err = os.Open(path); err != nil {
+				log.Println(err)
+			} else {
+				log.Println(path, "was rotated")
+			}
+			continue
+		}
+		if cur.Size() < offset {
+			log.Println(path, "was truncated")
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				log.Fatal(err)
+			}
+			restart(f)
+			r.Reset(f)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Command server serves search suggestions over HTTP.
+//
+//	GET  /suggest?q=app&limit=10
+//	POST /terms [{"term": "apple", "count": 2}]
+//
+// With -data, the terms are kept in a snapshot and a write-ahead log, so
+// that they survive a restart.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alextanhongpin/typeahead"
+)
+
+const (
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+func main() {
+	var (
+		addr    = flag.String("addr", ":8080", "the address to listen on")
+		source  = flag.String("source", "", "the default dictionary to load, e.g. /usr/share/dict/words")
+		data    = flag.String("data", "", "the snapshot to keep the terms in, e.g. terms.snapshot")
+		compact = flag.Duration("compact", 10*time.Minute, "how often to fold the write-ahead log into the snapshot")
+	)
+	flag.Parse()
+
+	var durable *typeahead.Durable[any]
+	if *data != "" {
+		d, err := typeahead.OpenDurable(*data, newRoot())
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer d.Close()
+		durable = d
+		log.Println("opened", *data, "with", d.Load().Len(), "terms")
+	}
+	srv := newServer(durable)
+	// The dictionary is only loaded into an empty store, so that its words
+	// are not counted again on every restart.
+	if *source != "" && srv.tree().Len() == 0 {
+		f, err := os.Open(*source)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var words int
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if err := srv.insert(scanner.Bytes(), 1); err != nil {
+				log.Fatal(err)
+			}
+			words++
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			log.Fatal(err)
+		}
+		log.Println("inserted", words, "words")
+	}
+	if srv.durable != nil {
+		go func() {
+			for range time.Tick(*compact) {
+				if err := srv.durable.Compact(); err != nil {
+					log.Println(err)
+				}
+			}
+		}()
+	}
+
+	log.Println("listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, srv))
+}
+
+// server keeps the terms in a concurrent tree, so that the suggestions are
+// served without waiting for the new terms to be added. The terms are kept in
+// durable instead when it is set.
+type server struct {
+	root    *typeahead.Concurrent[any]
+	durable *typeahead.Durable[any]
+	mux     *http.ServeMux
+}
+
+// newRoot returns an empty tree that matches the terms regardless of their
+// case, accents and spacing.
+func newRoot() *typeahead.Root[any] {
+	r := typeahead.New()
+	r.Normalizer = typeahead.Standard
+	return r
+}
+
+// newServer returns a server that keeps the terms in durable, or in memory
+// when it is nil.
+func newServer(durable *typeahead.Durable[any]) *server {
+	s := &server{
+		durable: durable,
+		mux:     http.NewServeMux(),
+	}
+	if durable == nil {
+		s.root = typeahead.NewConcurrent[any]()
+		s.root.Store(newRoot())
+	}
+	s.mux.HandleFunc("GET /suggest", s.suggest)
+	s.mux.HandleFunc("POST /terms", s.terms)
+	return s
+}
+
+// tree returns the current snapshot of the terms.
+func (s *server) tree() *typeahead.Root[any] {
+	if s.durable != nil {
+		return s.durable.Load()
+	}
+	return s.root.Load()
+}
+
+// insert raises the count of the term by n.
+func (s *server) insert(term []byte, n int) error {
+	if s.durable != nil {
+		return s.durable.Increment(term, n)
+	}
+	s.root.Increment(term, n)
+	return nil
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+type suggestion struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+type suggestResponse struct {
+	Query       string       `json:"query"`
+	Suggestions []suggestion `json:"suggestions"`
+}
+
+func (s *server) suggest(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	limit := defaultLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLimit)
+	}
+
+	result := s.tree().TopK([]byte(q), limit)
+
+	res := suggestResponse{
+		Query:       q,
+		Suggestions: make([]suggestion, len(result)),
+	}
+	for i, r := range result {
+		res.Suggestions[i] = suggestion{Term: string(r.Display), Count: r.Count}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+type term struct {
+	Term string `json:"term"`
+	// Count is a pointer, so that a missing count can be told apart from
+	// zero.
+	Count *int `json:"count"`
+}
+
+// terms inserts the given terms, or increments their counts when they already
+// exist. A missing count is treated as one, and a given count must be
+// positive and at most typeahead.MaxCount.
+func (s *server) terms(w http.ResponseWriter, r *http.Request) {
+	var req []term
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	counts := make([]int, len(req))
+	for i, t := range req {
+		counts[i] = 1
+		if t.Count != nil {
+			counts[i] = *t.Count
+		}
+		if t.Term == "" || counts[i] <= 0 || counts[i] > typeahead.MaxCount {
+			http.Error(w, "terms must be non-empty with a positive count of at most "+strconv.Itoa(typeahead.MaxCount), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for i, t := range req {
+		if err := s.insert([]byte(t.Term), counts[i]); err != nil {
+			log.Println(err)
+			http.Error(w, "failed to store the terms", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"inserted": len(req)})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println(err)
+	}
+}
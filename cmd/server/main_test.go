@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	srv := httptest.NewServer(newServer(nil))
+	defer srv.Close()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			body := `[{"term": "Apple", "count": 2}, {"term": "apricot"}, {"term": "banana"}]`
+			res, err := http.Post(srv.URL+"/terms", "application/json", strings.NewReader(body))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("POST /terms: got status %d", res.StatusCode)
+			}
+		})
+		wg.Go(func() {
+			res, err := http.Get(srv.URL + "/suggest?q=a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			res.Body.Close()
+		})
+	}
+	wg.Wait()
+
+	res, err := http.Get(srv.URL + "/suggest?q=ap&limit=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var got suggestResponse
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []suggestion{{"Apple", 8}, {"apricot", 4}}
+	if len(got.Suggestions) != len(want) {
+		t.Fatalf("got %v, want %v", got.Suggestions, want)
+	}
+	for i := range want {
+		if got.Suggestions[i] != want[i] {
+			t.Fatalf("got %v, want %v", got.Suggestions, want)
+		}
+	}
+
+	res, err = http.Get(srv.URL + "/suggest?q=ap&limit=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("got status %d for an invalid limit", res.StatusCode)
+	}
+
+	for _, body := range []string{`[{"term": "cherry", "count": 0}]`, `[{"term": "cherry", "count": -1}]`, `[{"term": "cherry", "count": 2147483648}]`, `[{"term": ""}]`} {
+		res, err := http.Post(srv.URL+"/terms", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusBadRequest {
+			t.Fatalf("POST /terms %s: got status %d", body, res.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package typeahead
+
+import (
+	"bytes"
+	"iter"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Concurrent is a tree that can be read while it is being written to. The
+// writers copy the nodes along the path of the key before changing them and
+// then publish the new root atomically, so the readers never block and always
+// see a consistent snapshot of the tree.
+type Concurrent[V any] struct {
+	// mu serialises the writers.
+	mu   sync.Mutex
+	root atomic.Pointer[Root[V]]
+}
+
+// NewConcurrent returns a new concurrent tree that stores values of type V.
+func NewConcurrent[V any]() *Concurrent[V] {
+	c := new(Concurrent[V])
+	c.root.Store(NewOf[V]())
+	return c
+}
+
+// Load returns the current snapshot of the tree. The snapshot is not affected
+// by later writes, and must not be modified.
+func (c *Concurrent[V]) Load() *Root[V] {
+	return c.root.Load()
+}
+
+// Store replaces the tree with r, e.g. after it was loaded from disk or to set
+// its HalfLife. r must not be modified afterwards.
+func (c *Concurrent[V]) Store(r *Root[V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.root.Store(r)
+}
+
+// Insert adds a key value pair into the tree, see Root.Insert.
+func (c *Concurrent[V]) Insert(key []byte, value V) {
+	c.update(key, func(r *Root[V]) { r.Insert(key, value) })
+}
+
+// Increment raises the frequency of the key by n, see Root.Increment.
+func (c *Concurrent[V]) Increment(key []byte, n int) {
+	c.update(key, func(r *Root[V]) { r.Increment(key, n) })
+}
+
+// Delete removes the key from the tree, see Root.Delete.
+func (c *Concurrent[V]) Delete(key []byte) (ok bool) {
+	c.update(key, func(r *Root[V]) { ok = r.Delete(key) })
+	return
+}
+
+// Decrement lowers the frequency of the key by n, see Root.Decrement.
+func (c *Concurrent[V]) Decrement(key []byte, n int) (ok bool) {
+	c.update(key, func(r *Root[V]) { ok = r.Decrement(key, n) })
+	return
+}
+
+// Get returns the value and the frequency stored for the exact key.
+func (c *Concurrent[V]) Get(key []byte) (value V, count int, ok bool) {
+	return c.Load().Get(key)
+}
+
+// Contains reports whether the exact key is stored in the tree.
+func (c *Concurrent[V]) Contains(key []byte) bool {
+	return c.Load().Contains(key)
+}
+
+// Len returns the number of distinct keys in the tree.
+func (c *Concurrent[V]) Len() int {
+	return c.Load().Len()
+}
+
+// FindRecursive returns the keys of all the words that complete the prefix.
+func (c *Concurrent[V]) FindRecursive(key []byte) [][]byte {
+	return c.Load().FindRecursive(key)
+}
+
+// TopK returns the k most frequent words that start with the prefix.
+func (c *Concurrent[V]) TopK(prefix []byte, k int) []Suggestion[V] {
+	return c.Load().TopK(prefix, k)
+}
+
+// TopKDecayed returns the k words with the highest decayed score that start
+// with the prefix.
+func (c *Concurrent[V]) TopKDecayed(prefix []byte, k int, at time.Time) []Suggestion[V] {
+	return c.Load().TopKDecayed(prefix, k, at)
+}
+
+// FuzzyComplete returns up to k words that start with a prefix within
+// maxEdits edits of the given prefix.
+func (c *Concurrent[V]) FuzzyComplete(prefix []byte, maxEdits, k int) []Suggestion[V] {
+	return c.Load().FuzzyComplete(prefix, maxEdits, k)
+}
+
+// SubstringSearch returns the words that contain the pattern anywhere.
+func (c *Concurrent[V]) SubstringSearch(pattern []byte) []Suggestion[V] {
+	return c.Load().SubstringSearch(pattern)
+}
+
+// Suggest returns the k most frequent words that start with the query, or
+// else the words that contain it.
+func (c *Concurrent[V]) Suggest(query []byte, k int) []Suggestion[V] {
+	return c.Load().Suggest(query, k)
+}
+
+// Range calls fn for the words whose keys are in [from, to) in lexicographic
+// order, see Root.Range.
+func (c *Concurrent[V]) Range(from, to []byte, fn func(key []byte, edge Edge[V]) bool) {
+	c.Load().Range(from, to, fn)
+}
+
+// Prefix returns an iterator over the words that start with the prefix, see
+// Root.Prefix. It iterates over the snapshot taken when it is called.
+func (c *Concurrent[V]) Prefix(p []byte) iter.Seq2[[]byte, Edge[V]] {
+	return c.Load().Prefix(p)
+}
+
+// CompleteAfter returns the page of the words that start with the prefix
+// following the cursor, see Root.CompleteAfter.
+func (c *Concurrent[V]) CompleteAfter(prefix []byte, cur Cursor, limit int) ([]Suggestion[V], Cursor, error) {
+	return c.Load().CompleteAfter(prefix, cur, limit)
+}
+
+// update applies fn to a copy of the current root whose nodes along the path
+// of the key are private to the writer, and then publishes the copy.
+func (c *Concurrent[V]) update(key []byte, fn func(r *Root[V])) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r := new(Root[V])
+	*r = *c.root.Load()
+	clonePath(&(r.Node), r.normalize(key))
+	fn(r)
+	c.root.Store(r)
+}
+
+// clonePath copies the edges of every node that a write of the key may
+// change. The subtrees hanging off the path are left shared, as the writes
+// only move them around without changing them.
+func clonePath[V any](n *Node[V], key []byte) {
+	for {
+		n.Edges = slices.Clone(n.Edges)
+		if len(key) == 0 {
+			return
+		}
+		next := n.edge(key)
+		if next == nil || !bytes.HasPrefix(key, next.Key) {
+			return
+		}
+		key = key[len(next.Key):]
+		n = &(next.Node)
+	}
+}
@@ -0,0 +1,53 @@
+package typeahead
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrent(t *testing.T) {
+	c := NewConcurrent[int]()
+	for i := range 100 {
+		c.Insert(fmt.Appendf(nil, "key%d", i), i)
+	}
+	snapshot := c.Load()
+
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Go(func() {
+			for i := range 100 {
+				c.Increment(fmt.Appendf(nil, "key%d", i), 1)
+				c.Insert(fmt.Appendf(nil, "new%d-%d", w, i), i)
+				if i%2 == 0 {
+					c.Delete(fmt.Appendf(nil, "new%d-%d", w, i))
+				}
+			}
+		})
+		wg.Go(func() {
+			for range 100 {
+				for _, s := range c.TopK([]byte("key"), 5) {
+					if s.Count < 1 {
+						t.Errorf("unexpected count %d for %q", s.Count, s.Key)
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := snapshot.Len(); n != 100 {
+		t.Fatalf("snapshot was modified, got %d keys", n)
+	}
+	checkNode(t, snapshot.Node)
+	checkNode(t, c.Load().Node)
+	if n := c.Len(); n != 300 {
+		t.Fatalf("got %d keys, want 300", n)
+	}
+	for i := range 100 {
+		v, n, ok := c.Get(fmt.Appendf(nil, "key%d", i))
+		if !ok || v != i || n != 5 {
+			t.Fatalf("key%d: got value %d count %d", i, v, n)
+		}
+	}
+}
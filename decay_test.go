@@ -0,0 +1,74 @@
+package typeahead
+
+import (
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestTopKDecayed(t *testing.T) {
+	day := 24 * time.Hour
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	root := NewOf[any]()
+	root.HalfLife = day
+	root.IncrementAt([]byte("apple"), 10, start)
+	root.IncrementAt([]byte("apricot"), 3, start.Add(3*day))
+
+	at := start.Add(3 * day)
+	got := root.TopKDecayed([]byte("ap"), 2, at)
+	if len(got) != 2 || string(got[0].Key) != "apricot" || string(got[1].Key) != "apple" {
+		t.Fatalf("unexpected ranking %v", got)
+	}
+	if got[0].Score != 3 || got[1].Score != 1.25 {
+		t.Fatalf("unexpected scores %v and %v", got[0].Score, got[1].Score)
+	}
+	if got := root.TopK([]byte("ap"), 1); string(got[0].Key) != "apple" {
+		t.Fatalf("TopK should still rank by count, got %v", got)
+	}
+
+	// A decrement may use up the decayed score of a word that is still
+	// stored, which then ranks last.
+	root.IncrementAt([]byte("avocado"), 2, at.Add(-day))
+	root.Decrement([]byte("avocado"), 1)
+	got = root.TopKDecayed([]byte("a"), 3, at)
+	if len(got) != 3 || string(got[2].Key) != "avocado" || got[2].Score != 0 {
+		t.Fatalf("got %v, want avocado last", got)
+	}
+
+	f := func(in [][]byte, days []uint8, k uint8) bool {
+		root := NewOf[any]()
+		root.HalfLife = day
+		for i, w := range words(in) {
+			var d int
+			if i < len(days) {
+				d = int(days[i] % 10)
+			}
+			root.IncrementAt(w, i%3+1, start.Add(time.Duration(d)*day))
+			if i%4 == 3 {
+				root.Decrement(w, 1)
+			}
+		}
+		at := start.Add(10 * day)
+		got := root.TopKDecayed(nil, int(k%8), at)
+		returned := make(map[string]bool)
+		for i, s := range got {
+			returned[string(s.Key)] = true
+			if i > 0 && s.Score > got[i-1].Score*(1+1e-9) {
+				return false
+			}
+		}
+		var rest int
+		ok := walk(root.Node.Edges, nil, func(key []byte, edge Edge[any]) bool {
+			score := edge.Decayed(root.HalfLife, at)
+			if !edge.Endword || returned[string(key)] {
+				return true
+			}
+			rest++
+			return len(got) == 0 || score <= got[len(got)-1].Score*(1+1e-9)
+		})
+		return ok && (len(got) == int(k%8) || rest == 0)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
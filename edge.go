@@ -1,24 +1,93 @@
 package typeahead
 
+import (
+	"math"
+	"time"
+)
+
 // Edge represents the edge of a node.
-type Edge struct {
+type Edge[V any] struct {
 	Count   int
 	Key     []byte
-	Value   any
-	Node    Node
+	Value   V
+	Node    Node[V]
 	Endword bool
+	// Score is the popularity of the word as of Updated. It decays over
+	// time, see Root.HalfLife.
+	Score   float64
+	Updated time.Time
+	// Display is the key that the word was first inserted by, when the
+	// normalizer of the tree changed it, e.g. "Café" for "cafe".
+	Display []byte
 }
 
 // NewEdge creates a new Edge with the given key value pair.
-func NewEdge(key []byte, value any) Edge {
-	return Edge{
+func NewEdge[V any](key []byte, value V) Edge[V] {
+	return Edge[V]{
 		Key:   key,
 		Value: value,
 		Count: 1,
-		Node:  NewNode(),
+		Node:  NewNode[V](),
 	}
 }
 
-func (e Edge) String() string {
+// display returns the display form of the word with the given key.
+func (e Edge[V]) display(key []byte) []byte {
+	if e.Display != nil {
+		return e.Display
+	}
+	return key
+}
+
+func (e Edge[V]) String() string {
 	return string(e.Key)
 }
+
+// Frequency returns the number of times the word that ends at this edge was
+// inserted. Count also includes the longer words passing through the edge,
+// so their share is subtracted.
+func (e Edge[V]) Frequency() int {
+	if !e.Endword {
+		return 0
+	}
+	n := e.Count
+	for _, child := range e.Node.Edges {
+		n -= child.Count
+	}
+	return n
+}
+
+// Decayed returns the score of the word at the given time, halving it every
+// halfLife since the word was last updated. A zero halfLife disables the
+// decay.
+func (e Edge[V]) Decayed(halfLife time.Duration, at time.Time) float64 {
+	if halfLife <= 0 || e.Score == 0 {
+		return e.Score
+	}
+	return e.Score * math.Exp2(-float64(at.Sub(e.Updated))/float64(halfLife))
+}
+
+// rank orders the words by their decayed score. Since every score decays at
+// the same rate, the order does not depend on the time it is read at:
+// log2(Score) - (at-Updated)/halfLife only differs between the words by the
+// terms that do not involve at. The words without a score, e.g. after a
+// decrement, rank last rather than not at all.
+func (e Edge[V]) rank(halfLife time.Duration) float64 {
+	if !e.Endword {
+		return math.Inf(-1)
+	}
+	if e.Score <= 0 {
+		return -math.MaxFloat64
+	}
+	r := math.Log2(e.Score)
+	if halfLife > 0 {
+		r += float64(e.Updated.UnixNano()) / float64(halfLife)
+	}
+	return r
+}
+
+// best returns the highest frequency of the words in the subtree of the edge,
+// including the edge itself.
+func (e Edge[V]) best() int {
+	return max(e.Frequency(), e.Node.Max)
+}
@@ -0,0 +1,100 @@
+package typeahead
+
+import (
+	"bytes"
+	"slices"
+	"unicode/utf8"
+)
+
+// FuzzyComplete returns up to k words that start with a prefix within
+// maxEdits Levenshtein edits of the given prefix, so that "alxe" still
+// completes to "alexander". The results are ranked by their edit distance,
+// then by their frequency. Distances are measured in runes, so that "é" is one
+// edit away from "e".
+func (r *Root[V]) FuzzyComplete(prefix []byte, maxEdits, k int) []Suggestion[V] {
+	if k <= 0 || maxEdits < 0 {
+		return nil
+	}
+	query := chars(r.normalizeQuery(prefix))
+	row := make([]int, len(query)+1)
+	for i := range row {
+		row[i] = i
+	}
+	var out []Suggestion[V]
+	fuzzy(r.Node.Edges, query, nil, row, len(query), maxEdits, &out)
+	slices.SortFunc(out, func(a, b Suggestion[V]) int {
+		if a.Distance != b.Distance {
+			return a.Distance - b.Distance
+		}
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return bytes.Compare(a.Key, b.Key)
+	})
+	return out[:min(len(out), k)]
+}
+
+// fuzzy walks the edges while carrying the Levenshtein row of the query
+// against the path so far. best is the smallest distance between the query
+// and any prefix of the path, which is the distance of every word below it.
+// A branch is pruned once no prefix of it can come within maxEdits. The edges
+// are walked by rune, as they never hold a fragment of one, see head.
+func fuzzy[V any](edges []Edge[V], query []rune, path []byte, row []int, best, maxEdits int, out *[]Suggestion[V]) {
+	for _, edge := range edges {
+		curr, dist := row, best
+		pruned := false
+		for _, c := range chars(edge.Key) {
+			curr = nextRow(curr, query, c)
+			dist = min(dist, curr[len(query)])
+			if dist > maxEdits && slices.Min(curr) > maxEdits {
+				pruned = true
+				break
+			}
+		}
+		if pruned {
+			continue
+		}
+		key := make([]byte, 0, len(path)+len(edge.Key))
+		key = append(append(key, path...), edge.Key...)
+		if edge.Endword && dist <= maxEdits {
+			*out = append(*out, Suggestion[V]{
+				Key:      key,
+				Display:  edge.display(key),
+				Count:    edge.Frequency(),
+				Value:    edge.Value,
+				Distance: dist,
+			})
+		}
+		fuzzy(edge.Node.Edges, query, key, curr, dist, maxEdits, out)
+	}
+}
+
+// chars returns the characters of the key, as head splits them. A byte that
+// is not valid UTF-8 becomes a negative rune of its own, so that two invalid
+// bytes only match when they are equal.
+func chars(key []byte) []rune {
+	out := make([]rune, 0, len(key))
+	for len(key) > 0 {
+		r, size := utf8.DecodeRune(key)
+		if r == utf8.RuneError && size == 1 {
+			r = -1 - rune(key[0])
+		}
+		out = append(out, r)
+		key = key[size:]
+	}
+	return out
+}
+
+// nextRow extends the Levenshtein row by one character of the path.
+func nextRow[T byte | rune](row []int, query []T, c T) []int {
+	next := make([]int, len(row))
+	next[0] = row[0] + 1
+	for i := 1; i < len(row); i++ {
+		cost := 1
+		if query[i-1] == c {
+			cost = 0
+		}
+		next[i] = min(next[i-1]+1, row[i]+1, row[i-1]+cost)
+	}
+	return next
+}
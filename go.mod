@@ -1,3 +1,5 @@
 module github.com/alextanhongpin/typeahead
 
 go 1.26.1
+
+require golang.org/x/text v0.40.0
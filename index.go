@@ -0,0 +1,398 @@
+package typeahead
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// The index is a flat, pointer-free layout of the tree that is queried in
+// place, so that it can be memory-mapped instead of being rebuilt:
+//
+//	header  magic "TAIX", version, offset of the root node, number of keys,
+//	        each as a little-endian uint32
+//	node    uint32 edge count, followed by the edges sorted by their keys
+//	edge    key offset, key length, count, frequency, highest frequency in
+//	        the subtree, offset of the child node, value offset and value
+//	        length, each as a little-endian uint32
+//
+// The keys, the values and the nodes are laid out children first, and every
+// offset is relative to the start of the index. A zero child offset marks a
+// leaf, and a zero frequency an edge that does not end a word.
+const (
+	indexMagic      = "TAIX"
+	indexVersion    = 2
+	indexHeaderSize = 16
+	indexEdgeSize   = 8 * 4
+)
+
+// Index is a read-only tree compiled by Root.WriteIndex. It holds the keys,
+// their frequencies and their values, but not their decayed scores. A corrupt
+// index cannot make the lookups panic, but may return incomplete results.
+type Index[V any] struct {
+	data []byte
+	root uint32
+	size int
+	// Codec decodes the values of the index. It defaults to JSONCodec.
+	Codec Codec[V]
+	// close releases the memory mapping, if any.
+	close func() error
+}
+
+// NewIndex returns the index stored in b, which must not be modified while
+// the index is in use.
+func NewIndex[V any](b []byte) (*Index[V], error) {
+	if len(b) < indexHeaderSize || string(b[:4]) != indexMagic {
+		return nil, fmt.Errorf("%w: not an index", ErrCorrupt)
+	}
+	if v := binary.LittleEndian.Uint32(b[4:]); v != indexVersion {
+		return nil, fmt.Errorf("%w: got index version %d, want %d", ErrVersion, v, indexVersion)
+	}
+	root := binary.LittleEndian.Uint32(b[8:])
+	if uint64(root)+4 > uint64(len(b)) {
+		return nil, fmt.Errorf("%w: root node out of range", ErrCorrupt)
+	}
+	return &Index[V]{
+		data: b,
+		root: root,
+		size: int(binary.LittleEndian.Uint32(b[12:])),
+	}, nil
+}
+
+// Close releases the index.
+func (x *Index[V]) Close() error {
+	if x.close == nil {
+		return nil
+	}
+	err := x.close()
+	x.close, x.data = nil, nil
+	return err
+}
+
+// WriteIndex compiles the tree into an index and writes it to w. The index
+// can then be opened with OpenIndex or NewIndex. The index keeps neither the
+// Normalizer nor the display forms of the tree, so the queries must be
+// normalized by the caller. The counts are kept in 32 bits, so a tree whose
+// counts do not fit cannot be compiled.
+func (r *Root[V]) WriteIndex(w io.Writer) (int64, error) {
+	buf := make([]byte, indexHeaderSize)
+	buf, root, err := compile(buf, r.codec(), r.Node)
+	if err != nil {
+		return 0, err
+	}
+	if len(buf) > math.MaxUint32 {
+		return 0, errors.New("typeahead: index larger than 4GB")
+	}
+	copy(buf, indexMagic)
+	binary.LittleEndian.PutUint32(buf[4:], indexVersion)
+	binary.LittleEndian.PutUint32(buf[8:], root)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(r.Len()))
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// compile appends the node to buf, children first, and returns the offset of
+// the node.
+func compile[V any](buf []byte, codec Codec[V], n Node[V]) ([]byte, uint32, error) {
+	edges := make([]uint32, 0, len(n.Edges)*8)
+	for _, edge := range n.Edges {
+		// The count of the edge bounds the frequencies below it.
+		if edge.Count > math.MaxUint32 {
+			return nil, 0, fmt.Errorf("typeahead: count %d of %q does not fit in the index", edge.Count, edge.Key)
+		}
+		var child uint32
+		if !edge.Node.IsLeaf() {
+			var err error
+			if buf, child, err = compile(buf, codec, edge.Node); err != nil {
+				return nil, 0, err
+			}
+		}
+		keyOff := uint32(len(buf))
+		buf = append(buf, edge.Key...)
+
+		var valOff, valLen uint32
+		if edge.Endword && any(edge.Value) != nil {
+			b, err := codec.Marshal(edge.Value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("typeahead: encoding the value of %q: %w", edge.Key, err)
+			}
+			valOff, valLen = uint32(len(buf)), uint32(len(b))
+			buf = append(buf, b...)
+		}
+		edges = append(edges, keyOff, uint32(len(edge.Key)), uint32(edge.Count),
+			uint32(edge.Frequency()), uint32(edge.best()), child, valOff, valLen)
+	}
+
+	// Sort the edges by their keys, so that they can be searched.
+	order := make([]int, len(n.Edges))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return bytes.Compare(n.Edges[order[i]].Key, n.Edges[order[j]].Key) < 0
+	})
+	off := uint32(len(buf))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(n.Edges)))
+	for _, i := range order {
+		for _, v := range edges[i*8 : i*8+8] {
+			buf = binary.LittleEndian.AppendUint32(buf, v)
+		}
+	}
+	return buf, off, nil
+}
+
+// indexEdge is a decoded edge of the index.
+type indexEdge struct {
+	key   []byte
+	count int
+	freq  int
+	best  int
+	child uint32
+	value []byte
+}
+
+// u32 reads the uint32 at the offset, or returns false when it is out of
+// range.
+func (x *Index[V]) u32(off uint64) (uint32, bool) {
+	if off+4 > uint64(len(x.data)) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(x.data[off:]), true
+}
+
+// slice returns the bytes at the offset, or false when they are out of
+// range.
+func (x *Index[V]) slice(off, n uint32) ([]byte, bool) {
+	if uint64(off)+uint64(n) > uint64(len(x.data)) {
+		return nil, false
+	}
+	return x.data[off : off+n : off+n], true
+}
+
+// edges returns the number of edges of the node at the offset.
+func (x *Index[V]) edges(node uint32) int {
+	n, ok := x.u32(uint64(node))
+	if !ok || uint64(n)*indexEdgeSize > uint64(len(x.data)) {
+		return 0
+	}
+	return int(n)
+}
+
+// edge decodes the i-th edge of the node at the offset.
+func (x *Index[V]) edge(node uint32, i int) (indexEdge, bool) {
+	base := uint64(node) + 4 + uint64(i)*indexEdgeSize
+	var f [8]uint32
+	for j := range f {
+		v, ok := x.u32(base + uint64(j)*4)
+		if !ok {
+			return indexEdge{}, false
+		}
+		f[j] = v
+	}
+	// The children are laid out before their parents, which also rules out
+	// cycles in a corrupt index.
+	key, ok := x.slice(f[0], f[1])
+	if !ok || len(key) == 0 || f[5] >= node {
+		return indexEdge{}, false
+	}
+	e := indexEdge{key: key, count: int(f[2]), freq: int(f[3]), best: int(f[4]), child: f[5]}
+	if f[7] > 0 {
+		if e.value, ok = x.slice(f[6], f[7]); !ok {
+			return indexEdge{}, false
+		}
+	}
+	return e, true
+}
+
+// find returns the edge of the node that starts with the first character of
+// the key, see head. It is among the edges that start with the bytes of the
+// character, which sort right after them.
+func (x *Index[V]) find(node uint32, key []byte) (indexEdge, bool) {
+	h := head(key)
+	n := x.edges(node)
+	i := sort.Search(n, func(i int) bool {
+		e, ok := x.edge(node, i)
+		return !ok || bytes.Compare(e.key, h) >= 0
+	})
+	for ; i < n; i++ {
+		e, ok := x.edge(node, i)
+		if !ok || !bytes.HasPrefix(e.key, h) {
+			break
+		}
+		if bytes.Equal(head(e.key), h) {
+			return e, true
+		}
+	}
+	return indexEdge{}, false
+}
+
+// children returns the edges of the node at the offset.
+func (x *Index[V]) children(node uint32) []indexEdge {
+	if node == 0 {
+		return nil
+	}
+	n := x.edges(node)
+	out := make([]indexEdge, 0, n)
+	for i := range n {
+		e, ok := x.edge(node, i)
+		if !ok {
+			break
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// seek walks down the index along the key, see the seek function of the tree.
+func (x *Index[V]) seek(key []byte) ([]byte, []indexEdge) {
+	var found int
+	node := x.root
+	for found < len(key) {
+		if node == 0 {
+			return nil, nil
+		}
+		rest := key[found:]
+		if !utf8.FullRune(rest) {
+			var out []indexEdge
+			for _, e := range x.children(node) {
+				if bytes.HasPrefix(e.key, rest) {
+					out = append(out, e)
+				}
+			}
+			if len(out) == 0 {
+				return nil, nil
+			}
+			return key[:found], out
+		}
+		next, ok := x.find(node, rest)
+		if !ok {
+			return nil, nil
+		}
+		if bytes.HasPrefix(next.key, rest) {
+			return key[:found], []indexEdge{next}
+		}
+		if !bytes.HasPrefix(rest, next.key) {
+			return nil, nil
+		}
+		found += len(next.key)
+		node = next.child
+	}
+	return key[:found], x.children(node)
+}
+
+// Len returns the number of distinct keys in the index.
+func (x *Index[V]) Len() int {
+	return x.size
+}
+
+// Get returns the value and the frequency stored for the exact key. When the
+// key is found but its value fails to decode, ok is true and err reports the
+// corruption.
+func (x *Index[V]) Get(key []byte) (value V, count int, ok bool, err error) {
+	e, ok := x.lookup(key)
+	if !ok {
+		return value, 0, false, nil
+	}
+	if value, err = x.value(e); err != nil {
+		return value, e.freq, true, fmt.Errorf("typeahead: decoding the value of %q: %w", key, err)
+	}
+	return value, e.freq, true, nil
+}
+
+// Contains reports whether the exact key is stored in the index.
+func (x *Index[V]) Contains(key []byte) bool {
+	_, ok := x.lookup(key)
+	return ok
+}
+
+// lookup returns the edge that ends the word of the exact key.
+func (x *Index[V]) lookup(key []byte) (indexEdge, bool) {
+	if len(key) == 0 {
+		return indexEdge{}, false
+	}
+	node := x.root
+	for {
+		e, ok := x.find(node, key)
+		if !ok || !bytes.HasPrefix(key, e.key) {
+			return indexEdge{}, false
+		}
+		if key = key[len(e.key):]; len(key) == 0 {
+			return e, e.freq > 0
+		}
+		if node = e.child; node == 0 {
+			return indexEdge{}, false
+		}
+	}
+}
+
+func (x *Index[V]) value(e indexEdge) (V, error) {
+	var zero V
+	if e.value == nil {
+		return zero, nil
+	}
+	if x.Codec == nil {
+		return JSONCodec[V]{}.Unmarshal(e.value)
+	}
+	return x.Codec.Unmarshal(e.value)
+}
+
+// FindRecursive returns the keys of all the words that complete the given
+// prefix, see Root.FindRecursive.
+func (x *Index[V]) FindRecursive(key []byte) [][]byte {
+	var out [][]byte
+	prefix, edges := x.seek(key)
+	x.walk(edges, prefix, func(k []byte, e indexEdge) {
+		if e.freq > 0 && !bytes.Equal(k, key) {
+			out = append(out, k)
+		}
+	})
+	return out
+}
+
+func (x *Index[V]) walk(edges []indexEdge, prefix []byte, fn func(key []byte, e indexEdge)) {
+	for _, e := range edges {
+		key := make([]byte, 0, len(prefix)+len(e.key))
+		key = append(append(key, prefix...), e.key...)
+		fn(key, e)
+		x.walk(x.children(e.child), key, fn)
+	}
+}
+
+// TopK returns the k most frequent words that start with the given prefix,
+// see Root.TopK. A value that fails to decode is left as the zero value, see
+// Get to detect it.
+func (x *Index[V]) TopK(prefix []byte, k int) []Suggestion[V] {
+	if k <= 0 {
+		return nil
+	}
+	var pq candidates[indexEdge]
+	push := func(path []byte, edges []indexEdge) {
+		for _, e := range edges {
+			key := make([]byte, 0, len(path)+len(e.key))
+			key = append(append(key, path...), e.key...)
+			heap.Push(&pq, candidate[indexEdge]{key: key, rank: float64(e.best), edge: e})
+		}
+	}
+	push(x.seek(prefix))
+
+	var out []Suggestion[V]
+	for pq.Len() > 0 && len(out) < k {
+		c := heap.Pop(&pq).(candidate[indexEdge])
+		if c.word {
+			value, _ := x.value(c.edge)
+			out = append(out, Suggestion[V]{Key: c.key, Display: c.key, Count: c.edge.freq, Value: value})
+			continue
+		}
+		if c.edge.freq > 0 {
+			heap.Push(&pq, candidate[indexEdge]{key: c.key, rank: float64(c.edge.freq), edge: c.edge, word: true})
+		}
+		push(c.key, x.children(c.edge.child))
+	}
+	return out
+}
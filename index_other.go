@@ -0,0 +1,15 @@
+//go:build !unix
+
+package typeahead
+
+import "os"
+
+// OpenIndex reads the index stored in the file. Memory mapping is only
+// supported on unix, so the file is read into memory instead.
+func OpenIndex[V any](path string) (*Index[V], error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewIndex[V](data)
+}
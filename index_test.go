@@ -0,0 +1,132 @@
+package typeahead
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.idx")
+	f := func(in [][]byte, prefix []byte, k uint8) bool {
+		root := NewOf[int]()
+		for i, w := range words(in) {
+			root.Insert(w, i)
+		}
+		var buf bytes.Buffer
+		if _, err := root.WriteIndex(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		x, err := OpenIndex[int](path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer x.Close()
+
+		if x.Len() != root.Len() {
+			return false
+		}
+		p := words([][]byte{prefix})[0]
+		if !slices.EqualFunc(x.TopK(p, int(k%8)), root.TopK(p, int(k%8)), func(a, b Suggestion[int]) bool {
+			return bytes.Equal(a.Key, b.Key) && a.Count == b.Count && a.Value == b.Value
+		}) {
+			return false
+		}
+		got, want := x.FindRecursive(p), root.FindRecursive(p)
+		slices.SortFunc(got, bytes.Compare)
+		slices.SortFunc(want, bytes.Compare)
+		if !slices.EqualFunc(got, want, bytes.Equal) {
+			return false
+		}
+		for _, w := range append(words(in), p) {
+			v1, n1, ok1, err := x.Get(w)
+			v2, n2, ok2 := root.Get(w)
+			if v1 != v2 || n1 != n2 || ok1 != ok2 || err != nil {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexCorrupt(t *testing.T) {
+	root := New()
+	for _, w := range []string{"alexander", "alexandra", "alexis", "banana", "band"} {
+		root.Insert([]byte(w), w)
+	}
+	var buf bytes.Buffer
+	if _, err := root.WriteIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for range 1000 {
+		b := bytes.Clone(buf.Bytes())
+		for range 4 {
+			b[rand.IntN(len(b))] = byte(rand.Uint32())
+		}
+		x, err := NewIndex[any](b)
+		if err != nil {
+			continue
+		}
+		// The lookups must not panic.
+		x.TopK(nil, 10)
+		x.FindRecursive([]byte("al"))
+		x.Get([]byte("band"))
+	}
+}
+
+func TestIndexDecodeError(t *testing.T) {
+	root := NewOf[string]()
+	root.Insert([]byte("apple"), "fruit")
+	var buf bytes.Buffer
+	if _, err := root.WriteIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// The values are strings, so they fail to decode as ints.
+	x, err := NewIndex[int](buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, n, ok, err := x.Get([]byte("apple")); !ok || n != 1 || err == nil {
+		t.Fatalf("got %d %t %v, want a decode error", n, ok, err)
+	}
+	if !x.Contains([]byte("apple")) {
+		t.Fatal("the key should be found despite its value")
+	}
+	if _, _, ok, err := x.Get([]byte("apricot")); ok || err != nil {
+		t.Fatalf("got %t %v for a missing key", ok, err)
+	}
+}
+
+func TestIndexLargeCount(t *testing.T) {
+	root := New()
+	root.Increment([]byte("the"), math.MaxUint32)
+	var buf bytes.Buffer
+	if _, err := root.WriteIndex(&buf); err != nil {
+		t.Fatal(err)
+	}
+	x, err := NewIndex[any](buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, n, _, _ := x.Get([]byte("the")); n != math.MaxUint32 {
+		t.Fatalf("got count %d", n)
+	}
+
+	// The edge of "the" holds the counts of both words, which overflow.
+	root.Increment([]byte("then"), 1)
+	if _, err := root.WriteIndex(io.Discard); err == nil {
+		t.Fatal("compiled a count that does not fit")
+	}
+}
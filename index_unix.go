@@ -0,0 +1,36 @@
+//go:build unix
+
+package typeahead
+
+import (
+	"os"
+	"syscall"
+)
+
+// OpenIndex memory-maps the index stored in the file, so that the processes
+// that open it share the page cache. The index must be closed after use.
+func OpenIndex[V any](path string) (*Index[V], error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < indexHeaderSize {
+		return NewIndex[V](nil)
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	x, err := NewIndex[V](data)
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	x.close = func() error { return syscall.Munmap(data) }
+	return x, nil
+}
@@ -0,0 +1,158 @@
+package typeahead
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// Format is the line format of a query log.
+type Format int
+
+const (
+	// Text logs one query per line, and each line counts once.
+	Text Format = iota
+	// TSV logs a query and its count separated by a tab. The counts of
+	// TSV and JSONL must be positive.
+	TSV
+	// JSONL logs one JSON object per line, e.g. {"q": "apple", "n": 2}. A
+	// missing count counts once.
+	JSONL
+)
+
+var formats = map[Format]string{
+	Text:  "text",
+	TSV:   "tsv",
+	JSONL: "jsonl",
+}
+
+func (f Format) String() string {
+	if s, ok := formats[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (f Format) MarshalText() ([]byte, error) {
+	if _, ok := formats[f]; !ok {
+		return nil, fmt.Errorf("typeahead: unknown format %d", int(f))
+	}
+	return []byte(f.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, so that the format can
+// be used as a flag with flag.TextVar.
+func (f *Format) UnmarshalText(text []byte) error {
+	for format, s := range formats {
+		if s == string(text) {
+			*f = format
+			return nil
+		}
+	}
+	return fmt.Errorf("typeahead: unknown format %q", text)
+}
+
+// LineError reports a malformed line of a query log.
+type LineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("typeahead: line %d %q: %v", e.Line, e.Text, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// Ingest reads a query log and adds the count of every query to the tree.
+// Malformed lines are skipped and reported in the returned error as
+// *LineError values, joined with errors.Join; only a failure to read stops
+// the ingestion. It returns the number of lines that were ingested.
+func (r *Root[V]) Ingest(rd io.Reader, format Format) (int, error) {
+	return ingest(rd, format, r.Increment)
+}
+
+// Ingest reads a query log into the tree, see Root.Ingest.
+func (c *Concurrent[V]) Ingest(rd io.Reader, format Format) (int, error) {
+	return ingest(rd, format, c.Increment)
+}
+
+func ingest(rd io.Reader, format Format, increment func(key []byte, n int)) (int, error) {
+	var (
+		errs     []error
+		ingested int
+		line     int
+	)
+	scanner := bufio.NewScanner(rd)
+	for scanner.Scan() {
+		line++
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		key, n, err := parseLine(b, format)
+		if err != nil {
+			errs = append(errs, &LineError{Line: line, Text: string(b), Err: err})
+			continue
+		}
+		increment(key, n)
+		ingested++
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return ingested, errors.Join(errs...)
+}
+
+// parseLine returns the query of the line and its count.
+func parseLine(b []byte, format Format) ([]byte, int, error) {
+	switch format {
+	case Text:
+		return b, 1, nil
+	case TSV:
+		i := bytes.LastIndexByte(b, '\t')
+		if i == -1 {
+			return nil, 0, errors.New("missing tab separated count")
+		}
+		key := bytes.TrimSpace(b[:i])
+		if len(key) == 0 {
+			return nil, 0, errors.New("empty query")
+		}
+		n, err := strconv.Atoi(string(bytes.TrimSpace(b[i+1:])))
+		if err != nil {
+			return nil, 0, err
+		}
+		if n <= 0 {
+			return nil, 0, fmt.Errorf("count %d is not positive", n)
+		}
+		return key, n, nil
+	case JSONL:
+		var entry struct {
+			Q string `json:"q"`
+			N *int   `json:"n"`
+		}
+		if err := json.Unmarshal(b, &entry); err != nil {
+			return nil, 0, err
+		}
+		if entry.Q == "" {
+			return nil, 0, errors.New("empty query")
+		}
+		if entry.N == nil {
+			return []byte(entry.Q), 1, nil
+		}
+		if *entry.N <= 0 {
+			return nil, 0, fmt.Errorf("count %d is not positive", *entry.N)
+		}
+		return []byte(entry.Q), *entry.N, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown format %d", int(format))
+	}
+}
@@ -0,0 +1,61 @@
+package typeahead
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIngest(t *testing.T) {
+	tests := []struct {
+		format   Format
+		log      string
+		ingested int
+		lines    []int
+	}{
+		{Text, "apple\n\napple\napricot\n", 3, nil},
+		{TSV, "apple\t2\napricot\t1\nbanana\napple\tx\n\t3\nbanana\t0\n", 2, []int{3, 4, 5, 6}},
+		{JSONL, `{"q": "apple", "n": 2}` + "\n" + `{"q": "apricot"}` + "\n{\n" + `{"n": 1}` + "\n" + `{"q": "banana", "n": -1}` + "\n" + `{"q": "banana", "n": 0}`, 2, []int{3, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format.String(), func(t *testing.T) {
+			root := New()
+			n, err := root.Ingest(strings.NewReader(tt.log), tt.format)
+			if n != tt.ingested {
+				t.Fatalf("ingested %d lines, want %d", n, tt.ingested)
+			}
+			var lines []int
+			if err != nil {
+				for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+					var lerr *LineError
+					if !errors.As(err, &lerr) {
+						t.Fatalf("unexpected error %v", err)
+					}
+					lines = append(lines, lerr.Line)
+				}
+			}
+			if len(lines) != len(tt.lines) {
+				t.Fatalf("got malformed lines %v, want %v", lines, tt.lines)
+			}
+			for i := range lines {
+				if lines[i] != tt.lines[i] {
+					t.Fatalf("got malformed lines %v, want %v", lines, tt.lines)
+				}
+			}
+			if _, count, _ := root.Get([]byte("apple")); count != 2 {
+				t.Fatalf("apple has count %d, want 2", count)
+			}
+			if _, count, _ := root.Get([]byte("apricot")); count != 1 {
+				t.Fatalf("apricot has count %d, want 1", count)
+			}
+			if root.Contains([]byte("banana")) {
+				t.Fatal("banana should not have been ingested")
+			}
+		})
+	}
+
+	var f Format
+	if err := f.UnmarshalText([]byte("jsonl")); err != nil || f != JSONL {
+		t.Fatalf("got format %v and error %v", f, err)
+	}
+}
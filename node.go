@@ -1,27 +1,191 @@
 package typeahead
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"math/bits"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Node holds an array of edge.
-type Node struct {
-	Edges []Edge
+type Node[V any] struct {
+	// Edges are sorted by their keys, so that the words are visited in
+	// lexicographic order.
+	Edges []Edge[V]
+	// Max caches the highest word frequency found below this node.
+	Max int
+	// MaxRank caches the highest rank of the decayed scores found below
+	// this node.
+	MaxRank float64
+	// children indexes the edges by their first byte when there are many of
+	// them, see childIndex.
+	children *childIndex
 }
 
 // NewNode returns a new node value.
-func NewNode() Node {
-	return Node{}
+func NewNode[V any]() Node[V] {
+	return Node[V]{MaxRank: math.Inf(-1)}
 }
 
 // IsLeaf returns true if the node does not have any edges.
-func (n Node) IsLeaf() bool {
+func (n Node[V]) IsLeaf() bool {
 	return len(n.Edges) == 0
 }
 
+// updateMax recomputes the cached maximum from the edges of the node.
+func (n *Node[V]) updateMax() {
+	n.Max = 0
+	for _, edge := range n.Edges {
+		n.Max = max(n.Max, edge.best())
+	}
+}
+
+// updateRank recomputes the cached maximum rank from the edges of the node.
+func (n *Node[V]) updateRank(halfLife time.Duration) {
+	n.MaxRank = math.Inf(-1)
+	for _, edge := range n.Edges {
+		n.MaxRank = max(n.MaxRank, edge.rank(halfLife), edge.Node.MaxRank)
+	}
+}
+
+// edge returns the edge that starts with the first character of the key, or
+// nil.
+func (n *Node[V]) edge(key []byte) *Edge[V] {
+	if i := n.index(key); i != -1 {
+		return &n.Edges[i]
+	}
+	return nil
+}
+
+// index returns the position of the edge that starts with the first character
+// of the key, or -1. It looks the first byte up in the child index, if any,
+// and otherwise searches the sorted edges.
+func (n *Node[V]) index(key []byte) int {
+	x := n.children
+	if x == nil || x.n != len(n.Edges) || len(key) == 0 {
+		return index(n.Edges, key)
+	}
+	lo, hi, ok := x.run(key[0])
+	if !ok {
+		return -1
+	}
+	// An ASCII byte is a character of its own, so it starts a single edge.
+	if key[0] < utf8.RuneSelf {
+		return lo
+	}
+	h := head(key)
+	for i := lo; i < hi; i++ {
+		if bytes.Equal(head(n.Edges[i].Key), h) {
+			return i
+		}
+	}
+	return -1
+}
+
+// index returns the position of the edge that starts with the first character
+// of the key, or -1. The edges are sorted by their keys, so it is among the
+// edges that start with the bytes of the character, which sort right after
+// them.
+func index[V any](edges []Edge[V], key []byte) int {
+	if len(key) == 0 {
+		return -1
+	}
+	h := head(key)
+	i, _ := slices.BinarySearchFunc(edges, h, compareKey)
+	for ; i < len(edges) && bytes.HasPrefix(edges[i].Key, h); i++ {
+		if bytes.Equal(head(edges[i].Key), h) {
+			return i
+		}
+	}
+	return -1
+}
+
+// insertEdge adds the edge to the node, keeping the edges sorted by their
+// keys.
+func (n *Node[V]) insertEdge(edge Edge[V]) {
+	i, _ := slices.BinarySearchFunc(n.Edges, edge.Key, compareKey)
+	n.Edges = slices.Insert(n.Edges, i, edge)
+	n.reindex()
+}
+
+// deleteEdge removes the edge at position i from the node.
+func (n *Node[V]) deleteEdge(i int) {
+	n.Edges = slices.Delete(n.Edges, i, i+1)
+	n.reindex()
+}
+
+// reindex rebuilds the child index after the edges of the node changed.
+func (n *Node[V]) reindex() {
+	n.children = nil
+	if len(n.Edges) >= childIndexMin {
+		n.children = newChildIndex(n.Edges)
+	}
+}
+
+// childIndexMin is the fanout from which the nodes index their edges, below
+// which the binary search over the edges is just as fast.
+var childIndexMin = 16
+
+// childIndex maps the first byte of a key to the run of edges that start with
+// it, in the style of the large nodes of an adaptive radix tree. A bitmap
+// marks the bytes that start an edge, and the rank of a byte in the bitmap is
+// the position of its run in offsets. A run holds several edges when their
+// runes share the leading byte.
+//
+// The index only depends on the order of the keys, so the copies of a node
+// share it, and it is replaced rather than changed.
+type childIndex struct {
+	bitmap  [4]uint64
+	offsets []uint32
+	// n is the number of edges the index was built for.
+	n int
+}
+
+func newChildIndex[V any](edges []Edge[V]) *childIndex {
+	x := &childIndex{n: len(edges)}
+	for i := range edges {
+		c := edges[i].Key[0]
+		if w, bit := c>>6, uint64(1)<<(c&63); x.bitmap[w]&bit == 0 {
+			x.bitmap[w] |= bit
+			x.offsets = append(x.offsets, uint32(i))
+		}
+	}
+	x.offsets = append(x.offsets, uint32(len(edges)))
+	return x
+}
+
+// run returns the positions [lo, hi) of the edges that start with the byte c.
+func (x *childIndex) run(c byte) (lo, hi int, ok bool) {
+	w, bit := c>>6, uint64(1)<<(c&63)
+	if x.bitmap[w]&bit == 0 {
+		return 0, 0, false
+	}
+	r := bits.OnesCount64(x.bitmap[w] & (bit - 1))
+	for _, b := range x.bitmap[:w] {
+		r += bits.OnesCount64(b)
+	}
+	return int(x.offsets[r]), int(x.offsets[r+1]), true
+}
+
+func compareKey[V any](e Edge[V], key []byte) int {
+	return bytes.Compare(e.Key, key)
+}
+
+// head returns the first character of the key, which is its first rune, or
+// its first byte when the key does not start with valid UTF-8. The edges of a
+// node start with distinct characters, and the keys are only split between
+// two characters, so that the edges never hold a fragment of a rune.
+func head(key []byte) []byte {
+	_, n := utf8.DecodeRune(key)
+	return key[:n]
+}
+
 // Print iteratively prints all the node edges.
-func (n Node) Print(depth int) {
+func (n Node[V]) Print(depth int) {
 	for _, edge := range n.Edges {
 		key, count := edge.Key, edge.Count
 		fmt.Printf("%s %s:%d\n", strings.Repeat(" ", depth*2), key, count)
@@ -0,0 +1,91 @@
+package typeahead
+
+import (
+	"bufio"
+	"math"
+	"math/rand/v2"
+	"os"
+	"testing"
+)
+
+// TestChildIndex runs the tree tests with every node of two edges or more
+// indexed, including the nodes whose runes share their leading byte.
+func TestChildIndex(t *testing.T) {
+	defer func(n int) { childIndexMin = n }(childIndexMin)
+	childIndexMin = 2
+	for name, test := range map[string]func(*testing.T){
+		"Delete":            TestDelete,
+		"Get":               TestGet,
+		"RuneSplits":        TestRuneSplits,
+		"RuneSplitsInvalid": TestRuneSplitsInvalid,
+		"Snapshot":          TestSnapshot,
+		"Concurrent":        TestConcurrent,
+	} {
+		t.Run(name, test)
+	}
+}
+
+// dictionary returns the words of /usr/share/dict/words, or random words of
+// mixed case and accents when it is missing, which have a similar fanout.
+func dictionary(b *testing.B) [][]byte {
+	b.Helper()
+	var out [][]byte
+	if f, err := os.Open("/usr/share/dict/words"); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			out = append(out, []byte(scanner.Text()))
+		}
+		if err := scanner.Err(); err != nil {
+			b.Fatal(err)
+		}
+		return out
+	}
+	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZéèêàçñö'")
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range 100000 {
+		w := make([]rune, 3+rng.IntN(8))
+		for i := range w {
+			w[i] = letters[rng.IntN(len(letters))]
+		}
+		out = append(out, []byte(string(w)))
+	}
+	return out
+}
+
+func BenchmarkChildIndex(b *testing.B) {
+	words := dictionary(b)
+	defer func(n int) { childIndexMin = n }(childIndexMin)
+	for _, mode := range []struct {
+		name string
+		min  int
+	}{
+		{"sorted", math.MaxInt},
+		{"adaptive", childIndexMin},
+	} {
+		childIndexMin = mode.min
+		b.Run(mode.name+"/insert", func(b *testing.B) {
+			for b.Loop() {
+				root := NewOf[struct{}]()
+				for _, w := range words {
+					root.Insert(w, struct{}{})
+				}
+			}
+		})
+		root := NewOf[struct{}]()
+		for _, w := range words {
+			root.Insert(w, struct{}{})
+		}
+		b.Run(mode.name+"/get", func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				root.Get(words[i%len(words)])
+			}
+		})
+		b.Run(mode.name+"/find", func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				w := words[i%len(words)]
+				root.Find(w[:min(len(w), 4)])
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package typeahead
+
+import (
+	"bytes"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Normalizer maps a key to the form that it is stored and looked up by, so
+// that "Café", "CAFE" and "cafe" are the same word. See Root.Normalizer.
+type Normalizer interface {
+	Normalize(key []byte) []byte
+}
+
+// NormalizerFunc is a function that is used as a Normalizer.
+type NormalizerFunc func(key []byte) []byte
+
+func (f NormalizerFunc) Normalize(key []byte) []byte {
+	return f(key)
+}
+
+var (
+	// Lowercase maps the letters to their lower case.
+	Lowercase Normalizer = NormalizerFunc(bytes.ToLower)
+
+	// NFKC applies the Unicode compatibility composition, which maps e.g.
+	// the full-width "Ａ" to "A" and the ligature "ﬁ" to "fi".
+	NFKC Normalizer = NormalizerFunc(norm.NFKC.Bytes)
+
+	// FoldDiacritics removes the accents and the other combining marks, so
+	// that "é" matches "e".
+	FoldDiacritics Normalizer = NormalizerFunc(foldDiacritics)
+
+	// CollapseSpace trims the whitespace around the key, and replaces every
+	// run of whitespace inside it with a single space.
+	CollapseSpace Normalizer = NormalizerFunc(collapseSpace)
+
+	// Standard applies NFKC, Lowercase, FoldDiacritics and CollapseSpace in
+	// that order.
+	Standard = Chain(NFKC, Lowercase, FoldDiacritics, CollapseSpace)
+)
+
+// Chain returns a Normalizer that applies the given ones in order.
+func Chain(normalizers ...Normalizer) Normalizer {
+	return NormalizerFunc(func(key []byte) []byte {
+		for _, n := range normalizers {
+			key = n.Normalize(key)
+		}
+		return key
+	})
+}
+
+func foldDiacritics(key []byte) []byte {
+	// The transformers keep state, so a new chain is needed for every call.
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	out, _, err := transform.Bytes(t, key)
+	if err != nil {
+		return key
+	}
+	return out
+}
+
+func collapseSpace(key []byte) []byte {
+	out := make([]byte, 0, len(key))
+	space := false
+	for len(key) > 0 {
+		r, size := utf8.DecodeRune(key)
+		if unicode.IsSpace(r) {
+			space = len(out) > 0
+		} else {
+			if space {
+				out = append(out, ' ')
+				space = false
+			}
+			out = append(out, key[:size]...)
+		}
+		key = key[size:]
+	}
+	return out
+}
+
+// normalize returns the key that is stored for the given one.
+func (r *Root[V]) normalize(key []byte) []byte {
+	if r.Normalizer == nil {
+		return key
+	}
+	return r.Normalizer.Normalize(key)
+}
+
+// normalizeQuery is normalize for a prefix that is being typed. The space
+// that ends it is kept, which CollapseSpace trims, so that "new " completes
+// "new york" but not "newark".
+func (r *Root[V]) normalizeQuery(prefix []byte) []byte {
+	key := r.normalize(prefix)
+	if len(key) == 0 {
+		return key
+	}
+	if last, _ := utf8.DecodeLastRune(prefix); !unicode.IsSpace(last) {
+		return key
+	}
+	if last, _ := utf8.DecodeLastRune(key); unicode.IsSpace(last) {
+		return key
+	}
+	return append(key[:len(key):len(key)], ' ')
+}
@@ -0,0 +1,130 @@
+package typeahead
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestStandard(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Café", "cafe"},
+		{"CAFÉ", "cafe"},
+		{"Ｔｏｋｙｏ", "tokyo"},
+		{"ﬁancé", "fiance"},
+		{"  New \t York\nCity ", "new york city"},
+		{"São Paulo", "sao paulo"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Standard.Normalize([]byte(tt.in)); string(got) != tt.want {
+			t.Errorf("Standard(%q): got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizer(t *testing.T) {
+	root := New()
+	root.Normalizer = Standard
+	root.Insert([]byte("Café"), nil)
+	root.Increment([]byte("cafe"), 2)
+	root.Insert([]byte("cafeteria"), nil)
+	root.Insert([]byte("CAFETERIA"), nil)
+
+	if _, count, ok := root.Get([]byte("CAFÉ")); !ok || count != 3 {
+		t.Fatalf("get: got %d %t, want 3", count, ok)
+	}
+	if root.Len() != 2 {
+		t.Fatalf("got %d keys, want 2", root.Len())
+	}
+
+	check := func(r *Root[any]) {
+		t.Helper()
+		got := r.TopK([]byte("  CAF"), 2)
+		if len(got) != 2 {
+			t.Fatalf("got %d suggestions, want 2", len(got))
+		}
+		if string(got[0].Key) != "cafe" || string(got[0].Display) != "Café" {
+			t.Fatalf("got %q displayed as %q, want \"Café\"", got[0].Key, got[0].Display)
+		}
+		// The key was first inserted in its normalized form.
+		if string(got[1].Display) != "cafeteria" {
+			t.Fatalf("got %q, want \"cafeteria\"", got[1].Display)
+		}
+		if got := r.FuzzyComplete([]byte("Cafè"), 0, 1); len(got) != 1 || string(got[0].Display) != "Café" {
+			t.Fatalf("fuzzy: got %v", got)
+		}
+	}
+	check(root)
+
+	var buf bytes.Buffer
+	if _, err := root.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := New()
+	got.Normalizer = Standard
+	if _, err := got.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	check(got)
+
+	c := NewConcurrent[any]()
+	c.Store(got)
+	c.Insert([]byte("Ｃａｆé au lait"), nil)
+	if !c.Contains([]byte("cafe au lait")) || !root.Contains([]byte("CAFE")) {
+		t.Fatal("missing key after a concurrent insert")
+	}
+	check(c.Load())
+
+	// Removing the word drops its display form.
+	root.Delete([]byte("cafe"))
+	root.Increment([]byte("cafe"), 5)
+	if got := root.TopK([]byte("cafe"), 1); string(got[0].Display) != "cafe" {
+		t.Fatalf("got %q after reinserting it, want \"cafe\"", got[0].Display)
+	}
+}
+
+func TestNormalizerDeleteBranch(t *testing.T) {
+	root := New()
+	root.Normalizer = Standard
+	for _, w := range []string{"Café", "cafes", "cafeteria"} {
+		root.Insert([]byte(w), nil)
+	}
+	// The edge of "cafe" stays as a branch to its two children, and must not
+	// keep the display form of the deleted word.
+	root.Delete([]byte("cafe"))
+	root.Insert([]byte("cafe"), nil)
+	if got := root.TopK([]byte("cafe"), 1); len(got) != 1 || string(got[0].Display) != "cafe" {
+		t.Fatalf("got %v after reinserting it, want \"cafe\"", got)
+	}
+}
+
+func TestNormalizerQuerySpace(t *testing.T) {
+	root := New()
+	root.Normalizer = Standard
+	root.Insert([]byte("New York"), nil)
+	root.Insert([]byte("Newark"), nil)
+
+	for _, prefix := range []string{"new ", "NEW\t", "new  "} {
+		got := root.TopK([]byte(prefix), 2)
+		if len(got) != 1 || string(got[0].Key) != "new york" {
+			t.Fatalf("TopK(%q): got %v, want only \"new york\"", prefix, got)
+		}
+		if got := root.FindRecursive([]byte(prefix)); len(got) != 1 {
+			t.Fatalf("FindRecursive(%q): got %q", prefix, got)
+		}
+	}
+	if got := root.TopK([]byte("new"), 2); len(got) != 2 {
+		t.Fatalf("got %v, want both words", got)
+	}
+	// A query of spaces only is empty, as the words are trimmed.
+	if got := root.TopK([]byte(" "), 2); len(got) != 2 {
+		t.Fatalf("got %v, want both words", got)
+	}
+	// The words are trimmed when they are inserted.
+	root.Insert([]byte("newark "), nil)
+	if root.Len() != 2 {
+		t.Fatalf("got %d keys, want 2", root.Len())
+	}
+}
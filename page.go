@@ -0,0 +1,134 @@
+package typeahead
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+)
+
+// ErrCursor is returned for a cursor that was not returned by CompleteAfter.
+var ErrCursor = errors.New("typeahead: invalid cursor")
+
+// Order is the order of the pages of CompleteAfter.
+type Order byte
+
+const (
+	// Lexicographic orders the words by their keys.
+	Lexicographic Order = iota
+	// Ranked orders the words by their frequency, then by their keys, as
+	// TopK does.
+	Ranked
+)
+
+// Cursor is an opaque continuation token of CompleteAfter. It holds the order
+// of the pages and the position of the last word returned, so that the next
+// page starts right after it, wherever it moved in the tree.
+type Cursor string
+
+// Start returns the cursor of the first page in the order.
+func (o Order) Start() Cursor {
+	return cursor{order: o}.encode()
+}
+
+// cursor is the decoded form of a Cursor. started is unset for the first
+// page, which follows no word.
+type cursor struct {
+	order   Order
+	started bool
+	count   int
+	key     []byte
+}
+
+func (c cursor) encode() Cursor {
+	b := []byte{byte(c.order)}
+	if c.started {
+		b[0] |= 0x80
+		if c.order == Ranked {
+			b = binary.AppendVarint(b, int64(c.count))
+		}
+		b = append(b, c.key...)
+	}
+	return Cursor(base64.RawURLEncoding.EncodeToString(b))
+}
+
+func (c Cursor) decode() (cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(string(c))
+	if err != nil || len(b) == 0 {
+		return cursor{}, ErrCursor
+	}
+	cur := cursor{order: Order(b[0] &^ 0x80), started: b[0]&0x80 != 0}
+	if cur.order != Lexicographic && cur.order != Ranked {
+		return cursor{}, ErrCursor
+	}
+	b = b[1:]
+	if !cur.started {
+		if len(b) > 0 {
+			return cursor{}, ErrCursor
+		}
+		return cur, nil
+	}
+	if cur.order == Ranked {
+		count, n := binary.Varint(b)
+		if n <= 0 {
+			return cursor{}, ErrCursor
+		}
+		cur.count, b = int(count), b[n:]
+	}
+	cur.key = b
+	return cur, nil
+}
+
+// CompleteAfter returns a page of up to limit words that start with the
+// prefix, following the word the cursor points at, together with the cursor
+// of the next page. The first page is requested with Order.Start, and the
+// next cursor is empty after the last page.
+//
+// The cursor holds the last word returned rather than an offset, so the words
+// inserted or deleted between the pages neither repeat nor skip the other
+// words. In the Ranked order, a word whose frequency changes between the
+// pages may move across the cursor and be returned again, or not at all. The
+// ranked pages also skip over the words of the previous pages, so the later
+// pages cost more.
+func (r *Root[V]) CompleteAfter(prefix []byte, cur Cursor, limit int) ([]Suggestion[V], Cursor, error) {
+	c, err := cur.decode()
+	if err != nil {
+		return nil, "", err
+	}
+	if limit <= 0 {
+		return nil, cur, nil
+	}
+	prefix = r.normalizeQuery(prefix)
+
+	// One more word is looked up to tell whether there is a next page.
+	var out []Suggestion[V]
+	switch c.order {
+	case Lexicographic:
+		var from []byte
+		if c.started {
+			from = append(c.key[:len(c.key):len(c.key)], 0)
+		}
+		path, edges := seek(&(r.Node), prefix)
+		rangeWalk(edges, path, from, nil, func(key []byte, edge Edge[V]) bool {
+			out = append(out, Suggestion[V]{Key: key, Display: edge.display(key), Count: edge.Frequency(), Value: edge.Value})
+			return len(out) <= limit
+		})
+	case Ranked:
+		var after func(rank float64, key []byte) bool
+		if c.started {
+			last := float64(c.count)
+			after = func(rank float64, key []byte) bool {
+				return rank < last || rank == last && bytes.Compare(key, c.key) > 0
+			}
+		}
+		out = topK(&(r.Node), prefix, limit+1, func(e *Edge[V]) (float64, float64) {
+			return float64(e.Frequency()), float64(e.best())
+		}, nil, after)
+	}
+	if len(out) <= limit {
+		return out, "", nil
+	}
+	out = out[:limit]
+	last := out[limit-1]
+	return out, cursor{order: c.order, started: true, count: last.Count, key: last.Key}.encode(), nil
+}
@@ -0,0 +1,100 @@
+package typeahead
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestCompleteAfter(t *testing.T) {
+	f := func(in, extra [][]byte, prefix []byte, limit uint8) bool {
+		root := NewOf[int]()
+		for i, w := range words(in) {
+			root.Insert(w, i)
+		}
+		prefix = words([][]byte{prefix})[0]
+		prefix = prefix[:len(prefix)%3]
+		size := int(limit%4) + 1
+
+		for _, order := range []Order{Lexicographic, Ranked} {
+			var want []string
+			if order == Lexicographic {
+				for key := range root.Prefix(prefix) {
+					want = append(want, string(key))
+				}
+			} else {
+				for _, s := range root.TopK(prefix, root.Len()) {
+					want = append(want, string(s.Key))
+				}
+			}
+
+			// Insert words that do not start with the prefix, or that are
+			// new, between the pages. The pages must still hold every word
+			// of the first page exactly once, in order.
+			inserted := words(extra)
+			var got []string
+			cur := order.Start()
+			for {
+				page, next, err := root.CompleteAfter(prefix, cur, size)
+				if err != nil {
+					t.Log(err)
+					return false
+				}
+				if len(page) > size || (next != "" && len(page) != size) {
+					t.Logf("got a page of %d, want %d", len(page), size)
+					return false
+				}
+				for _, s := range page {
+					got = append(got, string(s.Key))
+				}
+				if next == "" {
+					break
+				}
+				cur = next
+				if len(inserted) > 0 {
+					w := append([]byte("d"), inserted[0]...)
+					inserted = inserted[1:]
+					root.Insert(append(slices.Clone(prefix), w...), 0)
+				}
+			}
+			// The words inserted between the pages, here or for the
+			// previous order, may show up too.
+			isNew := func(s string) bool { return bytes.ContainsRune([]byte(s), 'd') }
+			got = slices.DeleteFunc(got, isNew)
+			want = slices.DeleteFunc(want, isNew)
+			if !slices.Equal(got, want) {
+				t.Logf("%v %q by %d: got %q, want %q", order, prefix, size, got, want)
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCompleteAfterCursor(t *testing.T) {
+	root := New()
+	root.Normalizer = Standard
+	for _, w := range []string{"Apple", "apricot", "avocado", "banana"} {
+		root.Insert([]byte(w), nil)
+	}
+	root.Increment([]byte("avocado"), 2)
+
+	page, next, err := root.CompleteAfter([]byte("A"), Ranked.Start(), 2)
+	if err != nil || len(page) != 2 || string(page[0].Key) != "avocado" || string(page[1].Display) != "Apple" {
+		t.Fatalf("got %v, %v", page, err)
+	}
+	page, next, err = root.CompleteAfter([]byte("A"), next, 2)
+	if err != nil || len(page) != 1 || string(page[0].Key) != "apricot" || next != "" {
+		t.Fatalf("got %v, %q, %v", page, next, err)
+	}
+
+	for _, cur := range []Cursor{"", "!", Cursor("AgA"), Lexicographic.Start() + "A"} {
+		if _, _, err := root.CompleteAfter(nil, cur, 1); err != ErrCursor {
+			t.Fatalf("%q: got %v, want %v", cur, err, ErrCursor)
+		}
+	}
+}
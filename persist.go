@@ -0,0 +1,106 @@
+package typeahead
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ErrNotFound is returned by Load when there is no snapshot at the path.
+var ErrNotFound = errors.New("typeahead: snapshot not found")
+
+// Save writes a snapshot of the tree to the path. The snapshot is written to
+// a temporary file that is synced and then renamed over the path, so that a
+// crash never leaves a partial snapshot behind.
+func Save[V any](path string, r *Root[V]) (err error) {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if _, err := r.WriteTo(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// syncDir persists the rename of a file in the directory.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Load reads the snapshot stored at the path, see Root.ReadFrom. The values
+// are decoded with JSONCodec; use ReadFrom for another codec. It returns an
+// error wrapping ErrNotFound when the file does not exist, and ErrCorrupt or
+// ErrVersion when it cannot be read.
+func Load[V any](path string) (*Root[V], error) {
+	r := NewOf[V]()
+	if _, err := load(path, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the snapshot stored at the path into r. It returns the id of the
+// snapshot, made of its size and checksum, which tells the snapshots apart.
+func load[V any](path string, r *Root[V]) (id uint64, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := r.ReadFrom(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	if n != fi.Size() {
+		return 0, fmt.Errorf("%s: %w: %d trailing bytes", path, ErrCorrupt, fi.Size()-n)
+	}
+	return snapshotID(f, n)
+}
+
+// snapshotID returns the id of the snapshot of size n read from f.
+func snapshotID(f *os.File, n int64) (uint64, error) {
+	b := make([]byte, 4)
+	if _, err := f.ReadAt(b, n-4); err != nil {
+		return 0, err
+	}
+	return uint64(n)<<32 | uint64(binary.LittleEndian.Uint32(b)), nil
+}
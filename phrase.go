@@ -0,0 +1,220 @@
+package typeahead
+
+import (
+	"bytes"
+	"container/heap"
+	"slices"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Phrases completes the phrases from any of their words, so that "york"
+// suggests "New York City". Every suffix of a phrase that starts at a word is
+// stored in a second tree, and refers back to the phrase.
+type Phrases[V any] struct {
+	// phrases holds the phrases, with their counts, values and display
+	// forms.
+	phrases *Root[V]
+	// suffixes holds the normalized suffixes, with the keys of the phrases
+	// that end with them as their values. The frequency of a suffix is the
+	// sum of the counts of its phrases, so that it bounds each of them.
+	suffixes *Root[[][]byte]
+}
+
+// NewPhrases returns an empty set of phrases, whose keys are mapped by the
+// normalizer, e.g. Standard. The normalizer may be nil.
+func NewPhrases[V any](normalizer Normalizer) *Phrases[V] {
+	p := &Phrases[V]{
+		phrases:  NewOf[V](),
+		suffixes: NewOf[[][]byte](),
+	}
+	p.phrases.Normalizer = normalizer
+	return p
+}
+
+// Insert adds a phrase with its value, see Root.Insert.
+func (p *Phrases[V]) Insert(phrase []byte, value V) {
+	key := p.phrases.normalize(phrase)
+	if len(key) == 0 {
+		return
+	}
+	p.phrases.Insert(phrase, value)
+	p.link(key, 1)
+}
+
+// Increment raises the count of the phrase by n, see Root.Increment.
+func (p *Phrases[V]) Increment(phrase []byte, n int) {
+	key := p.phrases.normalize(phrase)
+	if len(key) == 0 || n <= 0 {
+		return
+	}
+	p.phrases.Increment(phrase, n)
+	p.link(key, n)
+}
+
+// Delete removes the phrase. It reports whether the phrase was found.
+func (p *Phrases[V]) Delete(phrase []byte) bool {
+	key := p.phrases.normalize(phrase)
+	edge, ok := lookup(&(p.phrases.Node), key)
+	if !ok {
+		return false
+	}
+	p.phrases.Delete(key)
+	p.unlink(key, edge.Frequency(), true)
+	return true
+}
+
+// Decrement lowers the count of the phrase by n, see Root.Decrement.
+func (p *Phrases[V]) Decrement(phrase []byte, n int) bool {
+	key := p.phrases.normalize(phrase)
+	edge, ok := lookup(&(p.phrases.Node), key)
+	if !ok || n <= 0 {
+		return false
+	}
+	p.phrases.Decrement(key, n)
+	count := edge.Frequency()
+	p.unlink(key, min(n, count), n >= count)
+	return true
+}
+
+// Get returns the value and the count of the phrase.
+func (p *Phrases[V]) Get(phrase []byte) (value V, count int, ok bool) {
+	return p.phrases.Get(phrase)
+}
+
+// Len returns the number of distinct phrases.
+func (p *Phrases[V]) Len() int {
+	return p.phrases.Len()
+}
+
+// TopK returns the k most frequent phrases with a word that starts with the
+// query. A phrase is returned once, however many of its words match. Ties
+// are broken by the lexicographic order of the phrases.
+func (p *Phrases[V]) TopK(query []byte, k int) []Suggestion[V] {
+	if k <= 0 {
+		return nil
+	}
+	path, edges := seek(&(p.suffixes.Node), p.phrases.normalizeQuery(query))
+	var pq phraseQueue[V]
+	expand := func(path []byte, edges []Edge[[][]byte]) {
+		for i := range edges {
+			key := make([]byte, 0, len(path)+len(edges[i].Key))
+			key = append(append(key, path...), edges[i].Key...)
+			heap.Push(&pq, phraseCandidate[V]{key: key, rank: edges[i].best(), suffix: &edges[i]})
+		}
+	}
+	expand(path, edges)
+
+	seen := make(map[string]bool)
+	var out []Suggestion[V]
+	for pq.Len() > 0 && len(out) < k {
+		c := heap.Pop(&pq).(phraseCandidate[V])
+		if c.suffix == nil {
+			if seen[string(c.key)] {
+				continue
+			}
+			seen[string(c.key)] = true
+			out = append(out, Suggestion[V]{
+				Key:     c.key,
+				Display: c.phrase.display(c.key),
+				Count:   c.rank,
+				Value:   c.phrase.Value,
+			})
+			continue
+		}
+		if c.suffix.Endword {
+			for _, ref := range c.suffix.Value {
+				if phrase := edgeAt(&(p.phrases.Node), ref); phrase != nil && !seen[string(ref)] {
+					heap.Push(&pq, phraseCandidate[V]{key: ref, rank: phrase.Frequency(), phrase: phrase})
+				}
+			}
+		}
+		expand(c.key, c.suffix.Node.Edges)
+	}
+	return out
+}
+
+// link adds the phrase with the normalized key to its suffixes, and raises
+// their frequency by n.
+func (p *Phrases[V]) link(key []byte, n int) {
+	now := time.Now()
+	for _, i := range wordStarts(key) {
+		suffix := key[i:]
+		refs, _, _ := p.suffixes.Get(suffix)
+		if !slices.ContainsFunc(refs, func(ref []byte) bool { return bytes.Equal(ref, key) }) {
+			refs = append(slices.Clip(refs), bytes.Clone(key))
+		}
+		p.suffixes.insertAt(suffix, refs, n, now)
+	}
+}
+
+// unlink lowers the frequency of the suffixes of the phrase by n, and removes
+// the phrase from them when it is gone.
+func (p *Phrases[V]) unlink(key []byte, n int, gone bool) {
+	now := time.Now()
+	for _, i := range wordStarts(key) {
+		suffix := key[i:]
+		p.suffixes.decrementAt(suffix, n, now)
+		if edge := edgeAt(&(p.suffixes.Node), suffix); gone && edge != nil && edge.Endword {
+			edge.Value = slices.DeleteFunc(slices.Clone(edge.Value), func(ref []byte) bool {
+				return bytes.Equal(ref, key)
+			})
+		}
+	}
+}
+
+// wordStarts returns the offsets of the words of the key: the start of the key,
+// and every letter or digit that follows another character.
+func wordStarts(key []byte) []int {
+	starts := []int{0}
+	inWord := true
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRune(key[i:])
+		word := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if word && !inWord && i > 0 {
+			starts = append(starts, i)
+		}
+		inWord = word
+		i += size
+	}
+	return starts
+}
+
+// phraseCandidate is either a suffix whose subtree has not been expanded yet,
+// with the highest frequency in the subtree as its rank, or a phrase waiting
+// to be emitted, with its count as its rank.
+type phraseCandidate[V any] struct {
+	key    []byte
+	rank   int
+	suffix *Edge[[][]byte]
+	phrase *Edge[V]
+}
+
+type phraseQueue[V any] []phraseCandidate[V]
+
+func (q phraseQueue[V]) Len() int { return len(q) }
+
+// Less expands the suffixes before emitting a phrase of the same rank, as
+// their subtrees may hold a phrase that sorts before it.
+func (q phraseQueue[V]) Less(i, j int) bool {
+	if q[i].rank != q[j].rank {
+		return q[i].rank > q[j].rank
+	}
+	if (q[i].suffix == nil) != (q[j].suffix == nil) {
+		return q[i].suffix != nil
+	}
+	return bytes.Compare(q[i].key, q[j].key) < 0
+}
+
+func (q phraseQueue[V]) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *phraseQueue[V]) Push(x any) { *q = append(*q, x.(phraseCandidate[V])) }
+
+func (q *phraseQueue[V]) Pop() any {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,111 @@
+package typeahead
+
+import (
+	"bytes"
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+// phrases joins the random words into phrases of up to three words.
+func phrases(in [][]byte) [][]byte {
+	ws := words(in)
+	var out [][]byte
+	for i := 0; i < len(ws); i += 3 {
+		out = append(out, bytes.Join(ws[i:min(i+1+i%3, len(ws))], []byte(" ")))
+	}
+	return out
+}
+
+func TestPhrases(t *testing.T) {
+	f := func(in, del [][]byte, query []byte, k uint8) bool {
+		p := NewPhrases[int](nil)
+		freq := make(map[string]int)
+		for i, w := range phrases(in) {
+			if i%2 == 0 {
+				p.Insert(w, i)
+			} else {
+				p.Increment(w, i%4+1)
+			}
+			if len(w) > 0 {
+				freq[string(w)] += max(1, (i%2)*(i%4+1))
+			}
+		}
+		for i, w := range phrases(del) {
+			if i%2 == 0 {
+				p.Delete(w)
+				delete(freq, string(w))
+			} else if _, ok := freq[string(w)]; ok {
+				p.Decrement(w, 2)
+				if freq[string(w)] -= 2; freq[string(w)] <= 0 {
+					delete(freq, string(w))
+				}
+			}
+		}
+		checkNode(t, p.suffixes.Node)
+		if p.Len() != len(freq) {
+			return false
+		}
+
+		q := string(words([][]byte{query})[0])
+		var want []string
+		for _, phrase := range slices.Sorted(maps.Keys(freq)) {
+			for _, i := range wordStarts([]byte(phrase)) {
+				if strings.HasPrefix(phrase[i:], q) {
+					want = append(want, phrase)
+					break
+				}
+			}
+		}
+		slices.SortStableFunc(want, func(a, b string) int {
+			return cmp.Compare(freq[b], freq[a])
+		})
+		want = want[:min(len(want), int(k%8))]
+
+		got := p.TopK([]byte(q), int(k%8))
+		if len(got) != len(want) {
+			t.Logf("query %q: got %d phrases, want %q", q, len(got), want)
+			return false
+		}
+		for i := range want {
+			if string(got[i].Key) != want[i] || got[i].Count != freq[want[i]] {
+				t.Logf("query %q: got %q, want %q", q, got[i].Key, want[i])
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPhrasesInfix(t *testing.T) {
+	p := NewPhrases[any](Standard)
+	p.Increment([]byte("New York City"), 5)
+	p.Increment([]byte("York"), 2)
+	p.Increment([]byte("New York-York Bridge"), 1)
+	p.Insert([]byte("Newark"), nil)
+
+	got := p.TopK([]byte("york"), 10)
+	want := []string{"New York City", "York", "New York-York Bridge"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d suggestions, want %q", len(got), want)
+	}
+	for i := range want {
+		if string(got[i].Display) != want[i] {
+			t.Fatalf("got %q, want %q", got[i].Display, want[i])
+		}
+	}
+	if got := p.TopK([]byte("YORK C"), 10); len(got) != 1 || got[0].Count != 5 {
+		t.Fatalf("got %v", got)
+	}
+
+	p.Delete([]byte("new york city"))
+	if got := p.TopK([]byte("city"), 10); len(got) != 0 {
+		t.Fatalf("got %v after deleting the phrase", got)
+	}
+}
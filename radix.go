@@ -102,7 +102,8 @@ func (n *TrieNode) Add(key string) {
 		// We already have an exact match, update the count and return.
 		if child.key == key {
 			child.count++
-			// child.endword = true
+			// A branching node may now end a word too.
+			child.endword = true
 			break
 		}
 		// Set the node to be equal the current child with the given prefix.
@@ -121,12 +122,17 @@ func (n *TrieNode) Add(key string) {
 		if child.key[:i+1] == key {
 			// fmt.Println("condition 2", child.key, key, key[:i+1], child.key[:i+1])
 			oldKey := child.key
+
+			// The suffix takes over the children of the old node.
+			suffix := NewTrieNode(oldKey[i+1:])
+			suffix.endword = child.endword
+			suffix.count = child.count
+			suffix.children = child.children
+
 			child.key = oldKey[:i+1]
 			child.count++
-			// child.endword = true
-
-			// This must be endword too
-			child.children = append(child.children, NewTrieNode(oldKey[i+1:]))
+			child.endword = true
+			child.children = []*TrieNode{suffix}
 			break
 		}
 		// E.g. john and jane. We know the first 'j' is the prefix, and john is already in the trie.
@@ -241,3 +247,89 @@ func matchPrefix(s, t string) int {
 	}
 	return j
 }
+
+// Insert adds the key to the tree, see Add.
+func (n *TrieNode) Insert(key string) {
+	if key == "" {
+		return
+	}
+	n.Add(key)
+}
+
+// Contains reports whether the exact key was added to the tree.
+func (n *TrieNode) Contains(key string) bool {
+	path, nodes := n.seek(key)
+	return key != "" && len(nodes) == 1 && path+nodes[0].key == key && nodes[0].endword
+}
+
+// Complete returns up to limit keys that start with the prefix. A limit of
+// zero or less returns every completion.
+func (n *TrieNode) Complete(prefix string, limit int) []string {
+	var out []string
+	path, nodes := n.seek(prefix)
+	for _, node := range nodes {
+		if !node.collect(path, limit, &out) {
+			break
+		}
+	}
+	return out
+}
+
+// Len returns the number of keys below the node.
+func (n *TrieNode) Len() int {
+	var count int
+	for _, child := range n.children {
+		if child.endword {
+			count++
+		}
+		count += child.Len()
+	}
+	return count
+}
+
+// seek walks down the tree along the prefix. It returns the children that
+// hold every key starting with the prefix, and the part of the prefix that
+// leads up to them. The prefix may end in the middle of a child key.
+func (n *TrieNode) seek(prefix string) (string, []*TrieNode) {
+	node, found := n, 0
+	for found < len(prefix) {
+		rest := prefix[found:]
+		var next *TrieNode
+		for _, child := range node.children {
+			if len(child.key) > 0 && child.key[0] == rest[0] {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			return "", nil
+		}
+		if strings.HasPrefix(next.key, rest) {
+			return prefix[:found], []*TrieNode{next}
+		}
+		if !strings.HasPrefix(rest, next.key) {
+			return "", nil
+		}
+		found += len(next.key)
+		node = next
+	}
+	return prefix, node.children
+}
+
+// collect appends the keys of the node and its children to out, and reports
+// whether the limit has not been reached yet.
+func (n *TrieNode) collect(prefix string, limit int, out *[]string) bool {
+	key := prefix + n.key
+	if n.endword {
+		*out = append(*out, key)
+		if limit > 0 && len(*out) >= limit {
+			return false
+		}
+	}
+	for _, child := range n.children {
+		if !child.collect(key, limit, out) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package typeahead
+
+import (
+	"bytes"
+	"iter"
+)
+
+// Walk calls fn for every word in the tree in lexicographic order of the
+// keys, until fn returns false. The order is only defined for keys that are
+// valid UTF-8.
+func (r *Root[V]) Walk(fn func(key []byte, edge Edge[V]) bool) {
+	r.Range(nil, nil, fn)
+}
+
+// Range calls fn for the words whose keys are in [from, to) in lexicographic
+// order, until fn returns false. A nil to is unbounded. The bounds are
+// compared with the keys as they are stored, that is normalized.
+//
+// To page through the words, pass the last key of the previous page followed
+// by a zero byte as from, which is the first key that sorts after it.
+func (r *Root[V]) Range(from, to []byte, fn func(key []byte, edge Edge[V]) bool) {
+	rangeWalk(r.Node.Edges, nil, from, to, fn)
+}
+
+// All returns an iterator over the words in the tree in lexicographic order.
+func (r *Root[V]) All() iter.Seq2[[]byte, Edge[V]] {
+	return r.Prefix(nil)
+}
+
+// Prefix returns an iterator over the words that start with the prefix,
+// including the prefix itself, in lexicographic order.
+func (r *Root[V]) Prefix(p []byte) iter.Seq2[[]byte, Edge[V]] {
+	p = r.normalizeQuery(p)
+	return func(yield func([]byte, Edge[V]) bool) {
+		prefix, edges := seek(&(r.Node), p)
+		walk(edges, prefix, func(key []byte, edge Edge[V]) bool {
+			return !edge.Endword || yield(key, edge)
+		})
+	}
+}
+
+// rangeWalk is walk restricted to the words in [from, to). It skips the
+// subtrees that sort before from, and stops at the first key past to, as the
+// keys that follow in pre-order are greater still.
+func rangeWalk[V any](edges []Edge[V], prefix, from, to []byte, fn func(key []byte, edge Edge[V]) bool) bool {
+	for _, edge := range edges {
+		key := make([]byte, 0, len(prefix)+len(edge.Key))
+		key = append(append(key, prefix...), edge.Key...)
+		if to != nil && bytes.Compare(key, to) >= 0 {
+			return false
+		}
+		before := bytes.Compare(key, from) < 0
+		if before && !bytes.HasPrefix(from, key) {
+			continue
+		}
+		if edge.Endword && !before && !fn(key, edge) {
+			return false
+		}
+		if !rangeWalk(edge.Node.Edges, key, from, to, fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,124 @@
+package typeahead
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestRange(t *testing.T) {
+	f := func(in [][]byte, bounds [][]byte, page uint8) bool {
+		root := NewOf[int]()
+		set := make(map[string]bool)
+		for i, w := range append(words(in), runeWords(in)...) {
+			root.Insert(w, i)
+			if len(w) > 0 {
+				set[string(w)] = true
+			}
+		}
+		sorted := slices.Sorted(maps.Keys(set))
+
+		var got []string
+		for key := range root.All() {
+			got = append(got, string(key))
+		}
+		if !slices.Equal(got, sorted) {
+			t.Logf("All: got %q, want %q", got, sorted)
+			return false
+		}
+		if got := root.FindRecursive(nil); !slices.EqualFunc(got, sorted, func(a []byte, b string) bool { return string(a) == b }) {
+			t.Logf("FindRecursive: got %q, want %q", got, sorted)
+			return false
+		}
+
+		bounds = append(words(bounds), nil, nil)
+		from, to := bounds[0], bounds[1]
+		if len(to) == 0 {
+			to = nil
+		}
+		var want []string
+		for _, w := range sorted {
+			if w >= string(from) && (to == nil || w < string(to)) {
+				want = append(want, w)
+			}
+		}
+		got = nil
+		root.Range(from, to, func(key []byte, edge Edge[int]) bool {
+			got = append(got, string(key))
+			return true
+		})
+		if !slices.Equal(got, want) {
+			t.Logf("Range(%q, %q): got %q, want %q", from, to, got, want)
+			return false
+		}
+
+		want = nil
+		for _, w := range sorted {
+			if bytes.HasPrefix([]byte(w), from) {
+				want = append(want, w)
+			}
+		}
+		got = nil
+		for key, edge := range root.Prefix(from) {
+			if !edge.Endword {
+				return false
+			}
+			got = append(got, string(key))
+		}
+		if !slices.Equal(got, want) {
+			t.Logf("Prefix(%q): got %q, want %q", from, got, want)
+			return false
+		}
+
+		// Page through the words, starting after the last key of the
+		// previous page.
+		size := int(page%4) + 1
+		var cursor []byte
+		got = nil
+		for {
+			var n int
+			root.Range(cursor, nil, func(key []byte, edge Edge[int]) bool {
+				got = append(got, string(key))
+				cursor = append(key, 0)
+				n++
+				return n < size
+			})
+			if n < size {
+				break
+			}
+		}
+		if !slices.Equal(got, sorted) {
+			t.Logf("pages of %d: got %q, want %q", size, got, sorted)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRangeStop(t *testing.T) {
+	root := New()
+	for _, w := range []string{"b", "ab", "a", "abc", "c"} {
+		root.Insert([]byte(w), nil)
+	}
+	var got []string
+	root.Walk(func(key []byte, _ Edge[any]) bool {
+		got = append(got, string(key))
+		return len(got) < 3
+	})
+	if want := []string{"a", "ab", "abc"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	got = nil
+	for key := range root.Prefix([]byte("a")) {
+		got = append(got, string(key))
+		break
+	}
+	if want := []string{"a"}; !slices.Equal(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
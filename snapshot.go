@@ -0,0 +1,397 @@
+package typeahead
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+	"time"
+)
+
+// The snapshot format is made of:
+//
+//	header   magic "TAHD", version byte, half-life in nanoseconds as varint
+//	node     uvarint edge count, followed by the edges
+//	edge     uvarint key length, key, uvarint count, flags byte,
+//	         [score as float64 bits, updated as unix nanoseconds varint],
+//	         [uvarint value length, value],
+//	         [uvarint display length, display], the node of the edge
+//	trailer  CRC-32C of all the preceding bytes, little-endian
+//
+// The nodes are written in pre-order, and the cached maximums are recomputed
+// when the snapshot is read. Version 1 is version 2 without the display forms.
+// Both may split the keys in the middle of a rune, and are rebuilt when read.
+const (
+	snapshotMagic   = "TAHD"
+	snapshotVersion = 3
+
+	// maxDepth bounds the recursion when reading a snapshot.
+	maxDepth = 1 << 16
+)
+
+// The flags of an edge in a snapshot.
+const (
+	flagEndword = 1 << iota
+	flagValue
+	flagScore
+	flagDisplay
+)
+
+var (
+	// ErrCorrupt is returned when a snapshot is truncated or fails its
+	// checks.
+	ErrCorrupt = errors.New("typeahead: corrupt snapshot")
+	// ErrVersion is returned when a snapshot was written with an unsupported
+	// version of the format.
+	ErrVersion = errors.New("typeahead: unsupported snapshot version")
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Codec encodes the values of a tree in its snapshots.
+type Codec[V any] interface {
+	Marshal(v V) ([]byte, error)
+	Unmarshal(b []byte) (V, error)
+}
+
+// JSONCodec encodes the values as JSON. It is the default codec.
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Marshal(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[V]) Unmarshal(b []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+func (r *Root[V]) codec() Codec[V] {
+	if r.Codec == nil {
+		return JSONCodec[V]{}
+	}
+	return r.Codec
+}
+
+// WriteTo writes a snapshot of the tree to w. It implements io.WriterTo.
+func (r *Root[V]) WriteTo(w io.Writer) (int64, error) {
+	sw := &snapshotWriter{w: bufio.NewWriter(w), crc: crc32.New(castagnoli)}
+	sw.write([]byte(snapshotMagic))
+	sw.write([]byte{snapshotVersion})
+	sw.varint(int64(r.HalfLife))
+	if err := writeNode(sw, r.codec(), r.Node); err != nil {
+		return sw.n, err
+	}
+	sw.w.Write(binary.LittleEndian.AppendUint32(nil, sw.crc.Sum32()))
+	sw.n += 4
+	if sw.err == nil {
+		sw.err = sw.w.Flush()
+	}
+	return sw.n, sw.err
+}
+
+func writeNode[V any](sw *snapshotWriter, codec Codec[V], n Node[V]) error {
+	sw.uvarint(uint64(len(n.Edges)))
+	for _, edge := range n.Edges {
+		sw.uvarint(uint64(len(edge.Key)))
+		sw.write(edge.Key)
+		sw.uvarint(uint64(edge.Count))
+
+		var flags byte
+		var value []byte
+		if edge.Endword {
+			flags |= flagEndword
+			if edge.Score != 0 {
+				flags |= flagScore
+			}
+			if any(edge.Value) != nil {
+				b, err := codec.Marshal(edge.Value)
+				if err != nil {
+					return fmt.Errorf("typeahead: encoding the value of %q: %w", edge.Key, err)
+				}
+				flags |= flagValue
+				value = b
+			}
+			if edge.Display != nil {
+				flags |= flagDisplay
+			}
+		}
+		sw.write([]byte{flags})
+		if flags&flagScore != 0 {
+			sw.write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(edge.Score)))
+			sw.varint(edge.Updated.UnixNano())
+		}
+		if flags&flagValue != 0 {
+			sw.uvarint(uint64(len(value)))
+			sw.write(value)
+		}
+		if flags&flagDisplay != 0 {
+			sw.uvarint(uint64(len(edge.Display)))
+			sw.write(edge.Display)
+		}
+		if err := writeNode(sw, codec, edge.Node); err != nil {
+			return err
+		}
+	}
+	return sw.err
+}
+
+// ReadFrom replaces the tree with the snapshot read from r. It implements
+// io.ReaderFrom. Corrupt or truncated snapshots are rejected with an error
+// that wraps ErrCorrupt, and the tree is left unchanged.
+func (r *Root[V]) ReadFrom(rd io.Reader) (int64, error) {
+	sr := &snapshotReader{r: bufio.NewReader(rd), crc: crc32.New(castagnoli)}
+	magic := make([]byte, len(snapshotMagic)+1)
+	if err := sr.full(magic); err != nil {
+		return sr.n, err
+	}
+	if string(magic[:len(snapshotMagic)]) != snapshotMagic {
+		return sr.n, fmt.Errorf("%w: bad magic %q", ErrCorrupt, magic[:len(snapshotMagic)])
+	}
+	version := magic[len(snapshotMagic)]
+	if version < 1 || version > snapshotVersion {
+		return sr.n, fmt.Errorf("%w: got version %d, want at most %d", ErrVersion, version, snapshotVersion)
+	}
+	halfLife, err := sr.varint()
+	if err != nil {
+		return sr.n, err
+	}
+	if halfLife < 0 {
+		return sr.n, fmt.Errorf("%w: negative half-life %d", ErrCorrupt, halfLife)
+	}
+	node, err := readNode(sr, r.codec(), time.Duration(halfLife), version, 0)
+	if err != nil {
+		return sr.n, err
+	}
+	if version < 3 {
+		node = rebuild(node, time.Duration(halfLife))
+	}
+
+	sum := sr.crc.Sum32()
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(sr.r, trailer); err != nil {
+		return sr.n, fmt.Errorf("%w: %w in the checksum", ErrCorrupt, io.ErrUnexpectedEOF)
+	}
+	sr.n += 4
+	if got := binary.LittleEndian.Uint32(trailer); got != sum {
+		return sr.n, fmt.Errorf("%w: checksum mismatch, got %08x, want %08x", ErrCorrupt, got, sum)
+	}
+	r.Node = node
+	r.HalfLife = time.Duration(halfLife)
+	return sr.n, nil
+}
+
+func readNode[V any](sr *snapshotReader, codec Codec[V], halfLife time.Duration, version byte, depth int) (Node[V], error) {
+	var n Node[V]
+	if depth > maxDepth {
+		return n, fmt.Errorf("%w: tree deeper than %d at byte %d", ErrCorrupt, maxDepth, sr.n)
+	}
+	count, err := sr.uvarint()
+	if err != nil {
+		return n, err
+	}
+	// Every edge of a node starts with a different character.
+	seen := make(map[string]bool)
+	for range count {
+		var edge Edge[V]
+		if edge.Key, err = sr.bytes(); err != nil {
+			return n, err
+		}
+		if len(edge.Key) == 0 || seen[string(head(edge.Key))] {
+			return n, fmt.Errorf("%w: invalid key %q at byte %d", ErrCorrupt, edge.Key, sr.n)
+		}
+		seen[string(head(edge.Key))] = true
+		c, err := sr.uvarint()
+		if err != nil {
+			return n, err
+		}
+		if c > math.MaxInt {
+			return n, fmt.Errorf("%w: count %d of %q out of range", ErrCorrupt, c, edge.Key)
+		}
+		edge.Count = int(c)
+
+		flags, err := sr.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		known := byte(flagEndword | flagValue | flagScore | flagDisplay)
+		if version < 2 {
+			known &^= flagDisplay
+		}
+		if flags&^known != 0 || flags&flagEndword == 0 && flags != 0 {
+			return n, fmt.Errorf("%w: invalid flags %08b of %q", ErrCorrupt, flags, edge.Key)
+		}
+		edge.Endword = flags&flagEndword != 0
+		if flags&flagScore != 0 {
+			b := make([]byte, 8)
+			if err := sr.full(b); err != nil {
+				return n, err
+			}
+			edge.Score = math.Float64frombits(binary.LittleEndian.Uint64(b))
+			if edge.Score < 0 || math.IsNaN(edge.Score) || math.IsInf(edge.Score, 0) {
+				return n, fmt.Errorf("%w: invalid score %v of %q", ErrCorrupt, edge.Score, edge.Key)
+			}
+			updated, err := sr.varint()
+			if err != nil {
+				return n, err
+			}
+			edge.Updated = time.Unix(0, updated)
+		}
+		if flags&flagValue != 0 {
+			b, err := sr.bytes()
+			if err != nil {
+				return n, err
+			}
+			if edge.Value, err = codec.Unmarshal(b); err != nil {
+				return n, fmt.Errorf("%w: decoding the value of %q: %w", ErrCorrupt, edge.Key, err)
+			}
+		}
+		if flags&flagDisplay != 0 {
+			if edge.Display, err = sr.bytes(); err != nil {
+				return n, err
+			}
+		}
+		if edge.Node, err = readNode(sr, codec, halfLife, version, depth+1); err != nil {
+			return n, err
+		}
+
+		// The count covers the word itself and every word below it.
+		children := 0
+		for _, child := range edge.Node.Edges {
+			if child.Count > math.MaxInt-children {
+				return n, fmt.Errorf("%w: count of %q out of range", ErrCorrupt, edge.Key)
+			}
+			children += child.Count
+		}
+		if edge.Endword && edge.Count <= children || !edge.Endword && (edge.Count != children || len(edge.Node.Edges) < 2) {
+			return n, fmt.Errorf("%w: inconsistent counts at %q", ErrCorrupt, edge.Key)
+		}
+		n.Edges = append(n.Edges, edge)
+	}
+	// The edges of the older snapshots are in the order they were inserted.
+	slices.SortFunc(n.Edges, func(a, b Edge[V]) int { return bytes.Compare(a.Key, b.Key) })
+	n.reindex()
+	n.updateMax()
+	n.updateRank(halfLife)
+	return n, nil
+}
+
+// rebuild inserts the words of the node into a new node, so that the keys are
+// split between the characters.
+func rebuild[V any](n Node[V], halfLife time.Duration) Node[V] {
+	out := NewNode[V]()
+	walk(n.Edges, nil, func(key []byte, edge Edge[V]) bool {
+		if edge.Endword {
+			insert(&out, key, edge.Value, edge.Frequency())
+			word := edgeAt(&out, key)
+			word.Score, word.Updated, word.Display = edge.Score, edge.Updated, edge.Display
+			rescore(&out, key, 0, edge.Updated, halfLife)
+		}
+		return true
+	})
+	return out
+}
+
+// snapshotWriter keeps the first error and the checksum of what was written.
+type snapshotWriter struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	n   int64
+	err error
+}
+
+func (sw *snapshotWriter) write(b []byte) {
+	if sw.err != nil {
+		return
+	}
+	var n int
+	n, sw.err = sw.w.Write(b)
+	sw.crc.Write(b[:n])
+	sw.n += int64(n)
+}
+
+func (sw *snapshotWriter) uvarint(x uint64) {
+	sw.write(binary.AppendUvarint(nil, x))
+}
+
+func (sw *snapshotWriter) varint(x int64) {
+	sw.write(binary.AppendVarint(nil, x))
+}
+
+// snapshotReader keeps the checksum of what was read, and reports a premature
+// end of the input as corruption.
+type snapshotReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	n   int64
+}
+
+func (sr *snapshotReader) truncated(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w at byte %d", ErrCorrupt, io.ErrUnexpectedEOF, sr.n)
+	}
+	return err
+}
+
+func (sr *snapshotReader) ReadByte() (byte, error) {
+	b, err := sr.r.ReadByte()
+	if err != nil {
+		return 0, sr.truncated(err)
+	}
+	sr.crc.Write([]byte{b})
+	sr.n++
+	return b, nil
+}
+
+func (sr *snapshotReader) full(b []byte) error {
+	n, err := io.ReadFull(sr.r, b)
+	sr.crc.Write(b[:n])
+	sr.n += int64(n)
+	return sr.truncated(err)
+}
+
+func (sr *snapshotReader) uvarint() (uint64, error) {
+	x, err := binary.ReadUvarint(sr)
+	if err != nil && !errors.Is(err, ErrCorrupt) {
+		return 0, fmt.Errorf("%w: %w at byte %d", ErrCorrupt, err, sr.n)
+	}
+	return x, err
+}
+
+func (sr *snapshotReader) varint() (int64, error) {
+	x, err := binary.ReadVarint(sr)
+	if err != nil && !errors.Is(err, ErrCorrupt) {
+		return 0, fmt.Errorf("%w: %w at byte %d", ErrCorrupt, err, sr.n)
+	}
+	return x, err
+}
+
+// bytes reads a length-prefixed byte slice. The slice grows as the bytes are
+// read, so that a corrupt length cannot allocate more than the input holds.
+func (sr *snapshotReader) bytes() ([]byte, error) {
+	size, err := sr.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if size > math.MaxInt32 {
+		return nil, fmt.Errorf("%w: length %d out of range at byte %d", ErrCorrupt, size, sr.n)
+	}
+	b, err := io.ReadAll(io.LimitReader(sr.r, int64(size)))
+	sr.crc.Write(b)
+	sr.n += int64(len(b))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != int(size) {
+		return nil, sr.truncated(io.ErrUnexpectedEOF)
+	}
+	return b, nil
+}
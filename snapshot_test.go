@@ -0,0 +1,199 @@
+package typeahead
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	f := func(in, del [][]byte) bool {
+		root := NewOf[string]()
+		root.HalfLife = time.Hour
+		for i, w := range words(in) {
+			root.Insert(w, string(w)+"!")
+			if i%3 == 0 {
+				root.Increment(w, i)
+			}
+		}
+		for _, w := range words(del) {
+			root.Delete(w)
+		}
+		var buf bytes.Buffer
+		n, err := root.WriteTo(&buf)
+		if err != nil || n != int64(buf.Len()) {
+			t.Log(err)
+			return false
+		}
+
+		got := NewOf[string]()
+		m, err := got.ReadFrom(&buf)
+		if err != nil || m != n || got.HalfLife != time.Hour {
+			t.Log(err)
+			return false
+		}
+		checkNode(t, got.Node)
+		if got.Node.MaxRank != root.Node.MaxRank || got.Len() != root.Len() {
+			return false
+		}
+		return walk(root.Node.Edges, nil, func(key []byte, edge Edge[string]) bool {
+			if !edge.Endword {
+				return true
+			}
+			v, count, ok := got.Get(key)
+			return ok && v == edge.Value && count == edge.Frequency()
+		})
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotCorrupt(t *testing.T) {
+	root := New()
+	for _, w := range []string{"alexander", "alexandra", "alexis", "banana"} {
+		root.Insert([]byte(w), map[string]any{"id": w})
+	}
+	var buf bytes.Buffer
+	if _, err := root.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	for i := range b {
+		corrupt := bytes.Clone(b)
+		corrupt[i] ^= 0x40
+		if _, err := New().ReadFrom(bytes.NewReader(corrupt)); !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrVersion) {
+			t.Fatalf("flipping byte %d: got error %v", i, err)
+		}
+		if _, err := New().ReadFrom(bytes.NewReader(b[:i])); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("truncating at byte %d: got error %v", i, err)
+		}
+	}
+
+	version := bytes.Clone(b)
+	version[len(snapshotMagic)] = snapshotVersion + 1
+	if _, err := New().ReadFrom(bytes.NewReader(version)); !errors.Is(err, ErrVersion) {
+		t.Fatalf("got error %v, want ErrVersion", err)
+	}
+
+	// A snapshot without display forms reads the same as version 1.
+	v1 := bytes.Clone(b[:len(b)-4])
+	v1[len(snapshotMagic)] = 1
+	v1 = binary.LittleEndian.AppendUint32(v1, crc32.Checksum(v1, castagnoli))
+	if _, err := New().ReadFrom(bytes.NewReader(v1)); err != nil {
+		t.Fatalf("reading version 1: %v", err)
+	}
+
+	got := New()
+	if _, err := got.ReadFrom(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	if v, _, _ := got.Get([]byte("alexis")); v.(map[string]any)["id"] != "alexis" {
+		t.Fatalf("got value %v", v)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.snapshot")
+	if _, err := Load[any](path); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("got error %v, want ErrNotFound", err)
+	}
+
+	root := New()
+	root.Insert([]byte("alexander"), nil)
+	root.Insert([]byte("alexis"), "x")
+	for range 2 {
+		if err := Save(path, root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Load[any](path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, n, ok := got.Get([]byte("alexis")); !ok || v != "x" || n != 1 || got.Len() != 2 {
+		t.Fatalf("got value %v and count %d", v, n)
+	}
+	if matches, _ := filepath.Glob(path + ".tmp*"); len(matches) != 0 {
+		t.Fatalf("temporary files left behind: %v", matches)
+	}
+
+	b, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, append(b, 0), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load[any](path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("got error %v, want ErrCorrupt", err)
+	}
+}
+
+func FuzzLoad(f *testing.F) {
+	root := New()
+	for _, w := range []string{"alexander", "alexandra", "alexis", "banana"} {
+		root.Insert([]byte(w), w)
+	}
+	root.Increment([]byte("alexis"), 3)
+	var buf bytes.Buffer
+	if _, err := root.WriteTo(&buf); err != nil {
+		f.Fatal(err)
+	}
+	f.Add(buf.Bytes())
+	f.Add([]byte(snapshotMagic))
+	f.Add([]byte{})
+
+	path := filepath.Join(f.TempDir(), "fuzz.snapshot")
+	f.Fuzz(func(t *testing.T, b []byte) {
+		if err := os.WriteFile(path, b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Load[any](path)
+		if err != nil {
+			if !errors.Is(err, ErrCorrupt) && !errors.Is(err, ErrVersion) {
+				t.Fatalf("unexpected error %v", err)
+			}
+			return
+		}
+		checkNode(t, got.Node)
+
+		// A snapshot that loads can be written and read back.
+		var out bytes.Buffer
+		if _, err := got.WriteTo(&out); err != nil {
+			t.Fatal(err)
+		}
+		again := New()
+		if _, err := again.ReadFrom(&out); err != nil || again.Len() != got.Len() {
+			t.Fatalf("reading back the snapshot: %v", err)
+		}
+	})
+}
+
+func TestSnapshotLargeCount(t *testing.T) {
+	root := New()
+	if _, err := root.Ingest(strings.NewReader("the\t3000000000\nthen\t3000000000\n"), TSV); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := root.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := New()
+	if _, err := got.ReadFrom(&buf); err != nil {
+		t.Fatal(err)
+	}
+	checkNode(t, got.Node)
+	if _, n, ok := got.Get([]byte("the")); !ok || n != 3000000000 {
+		t.Fatalf("got count %d", n)
+	}
+	// The edge of "the" holds the counts of both words.
+	if e := got.Node.Edges[0]; e.Count != 6000000000 {
+		t.Fatalf("got count %d of %q", e.Count, e.Key)
+	}
+}
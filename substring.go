@@ -0,0 +1,159 @@
+package typeahead
+
+import (
+	"bytes"
+	"slices"
+	"unicode/utf8"
+)
+
+// SubstringSearch returns the words that contain the pattern anywhere, with
+// the byte ranges of the matches in their display forms, so that "york" finds
+// "New York".
+// The results are ranked by their frequency, then by their keys. Every word is
+// scanned, so it is slower than the prefix lookups.
+func (r *Root[V]) SubstringSearch(pattern []byte) []Suggestion[V] {
+	pattern = r.normalizeQuery(pattern)
+	if len(pattern) == 0 {
+		return nil
+	}
+	bm := newBoyerMoore(pattern)
+	var out []Suggestion[V]
+	walk(r.Node.Edges, nil, func(key []byte, edge Edge[V]) bool {
+		if !edge.Endword || len(key) < len(pattern) {
+			return true
+		}
+		if starts := bm.findAll(key); len(starts) > 0 {
+			offsets := make([][2]int, len(starts))
+			for i, start := range starts {
+				offsets[i] = [2]int{start, start + len(pattern)}
+			}
+			if edge.Display != nil {
+				offsets = r.displayOffsets(edge.Display, offsets)
+			}
+			out = append(out, Suggestion[V]{
+				Key:     key,
+				Display: edge.display(key),
+				Count:   edge.Frequency(),
+				Value:   edge.Value,
+				Offsets: offsets,
+			})
+		}
+		return true
+	})
+	slices.SortFunc(out, func(a, b Suggestion[V]) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return bytes.Compare(a.Key, b.Key)
+	})
+	return out
+}
+
+// displayOffsets maps the byte ranges of the matches in a key onto its
+// display form. Every rune boundary of the display form is paired with the
+// length of the key that the display form normalizes to up to it, and an
+// offset maps to the last boundary that does not go past it. The ranges then
+// cover the whitespace that was collapsed and the marks that were folded.
+func (r *Root[V]) displayOffsets(display []byte, offsets [][2]int) [][2]int {
+	bounds, lens := []int{0}, []int{0}
+	for i := 0; i < len(display); {
+		_, size := utf8.DecodeRune(display[i:])
+		i += size
+		bounds = append(bounds, i)
+		lens = append(lens, len(r.normalizeQuery(display[:i:i])))
+	}
+	at := func(offset int) int {
+		i := 0
+		for i+1 < len(lens) && lens[i+1] <= offset {
+			i++
+		}
+		return bounds[i]
+	}
+	out := make([][2]int, len(offsets))
+	for i, o := range offsets {
+		out[i] = [2]int{at(o[0]), at(o[1])}
+	}
+	return out
+}
+
+// Suggest returns the k most frequent words that start with the query, see
+// TopK. When no word does, it falls back to the words that contain the query,
+// see SubstringSearch.
+func (r *Root[V]) Suggest(query []byte, k int) []Suggestion[V] {
+	if out := r.TopK(query, k); len(out) > 0 || k <= 0 {
+		return out
+	}
+	out := r.SubstringSearch(query)
+	return out[:min(len(out), k)]
+}
+
+// boyerMoore finds a pattern with the bad character and the good suffix
+// rules, which skip over the text by up to the length of the pattern.
+type boyerMoore struct {
+	pattern []byte
+	// badChar holds the last position of every byte in the pattern, or -1.
+	badChar [256]int
+	// goodSuffix holds the shift for a mismatch right before position i,
+	// when pattern[i:] matched.
+	goodSuffix []int
+}
+
+func newBoyerMoore(pattern []byte) *boyerMoore {
+	m := len(pattern)
+	bm := &boyerMoore{pattern: pattern, goodSuffix: make([]int, m+1)}
+	for i := range bm.badChar {
+		bm.badChar[i] = -1
+	}
+	for i, c := range pattern {
+		bm.badChar[c] = i
+	}
+
+	// border[i] is the start of the widest border of pattern[i:], that is
+	// the longest proper suffix of it that is also a prefix of it.
+	border := make([]int, m+1)
+	i, j := m, m+1
+	border[i] = j
+	for i > 0 {
+		// The matched suffix reoccurs earlier in the pattern.
+		for j <= m && pattern[i-1] != pattern[j-1] {
+			if bm.goodSuffix[j] == 0 {
+				bm.goodSuffix[j] = j - i
+			}
+			j = border[j]
+		}
+		i, j = i-1, j-1
+		border[i] = j
+	}
+	// Otherwise, a prefix of the pattern matches a part of the suffix.
+	j = border[0]
+	for i := range bm.goodSuffix {
+		if bm.goodSuffix[i] == 0 {
+			bm.goodSuffix[i] = j
+		}
+		if i == j {
+			j = border[j]
+		}
+	}
+	return bm
+}
+
+// findAll returns the offsets of every match of the pattern in the text,
+// including the overlapping ones.
+func (bm *boyerMoore) findAll(text []byte) []int {
+	var out []int
+	m, n := len(bm.pattern), len(text)
+	for s := 0; s <= n-m; {
+		// Compare the pattern from its end.
+		j := m - 1
+		for j >= 0 && bm.pattern[j] == text[s+j] {
+			j--
+		}
+		if j < 0 {
+			out = append(out, s)
+			s += bm.goodSuffix[0]
+			continue
+		}
+		s += max(bm.goodSuffix[j+1], j-bm.badChar[text[s+j]])
+	}
+	return out
+}
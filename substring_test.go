@@ -0,0 +1,98 @@
+package typeahead
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"testing/quick"
+)
+
+func TestBoyerMoore(t *testing.T) {
+	f := func(text, pattern []byte) bool {
+		// Map onto a tiny alphabet, so that the pattern occurs often.
+		for i := range text {
+			text[i] = "ab"[text[i]%2]
+		}
+		pattern = words([][]byte{pattern})[0]
+		if len(pattern) == 0 {
+			return true
+		}
+		var want []int
+		for i := range text {
+			if bytes.HasPrefix(text[i:], pattern) {
+				want = append(want, i)
+			}
+		}
+		got := newBoyerMoore(pattern).findAll(text)
+		if !slices.Equal(got, want) {
+			t.Logf("%q in %q: got %v, want %v", pattern, text, got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	got := newBoyerMoore([]byte("AABA")).findAll([]byte("AABAACAADAABAABA"))
+	if want := []int{0, 9, 12}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSubstringSearch(t *testing.T) {
+	root := New()
+	root.Normalizer = Standard
+	root.Increment([]byte("New York"), 3)
+	root.Increment([]byte("York"), 5)
+	root.Increment([]byte("Yorkshire"), 1)
+	root.Increment([]byte("Boston"), 2)
+
+	got := root.SubstringSearch([]byte("YORK"))
+	want := []string{"york", "new york", "yorkshire"}
+	if len(got) != len(want) {
+		t.Fatalf("got %d results, want %q", len(got), want)
+	}
+	for i := range want {
+		if string(got[i].Key) != want[i] {
+			t.Fatalf("got %q, want %q", got[i].Key, want[i])
+		}
+	}
+	if !slices.Equal(got[1].Offsets, [][2]int{{4, 8}}) || string(got[1].Display) != "New York" {
+		t.Fatalf("got offsets %v of %q", got[1].Offsets, got[1].Display)
+	}
+
+	// The prefix lookup finds nothing, so the substring search takes over.
+	if got := root.Suggest([]byte("ston"), 5); len(got) != 1 || string(got[0].Key) != "boston" {
+		t.Fatalf("got %v", got)
+	}
+	if got := root.Suggest([]byte("yo"), 1); len(got) != 1 || got[0].Offsets != nil {
+		t.Fatalf("got %v", got)
+	}
+
+	// The offsets index the display form, whose bytes do not line up with
+	// the normalized key.
+	root.Insert([]byte("Café  au lait"), nil)
+	root.Insert([]byte("  Ｅｃｌａｉｒ"), nil)
+	root.Insert([]byte("Cafe\u0301 Creme"), nil)
+	for _, tt := range []struct {
+		pattern string
+		want    []string
+	}{
+		{"AU", []string{"au"}},
+		{"e au", []string{"é  au"}},
+		{"cafe ", []string{"Café  ", "Cafe\u0301 "}},
+		{"e c", []string{"e\u0301 C"}},
+		{"clair", []string{"ｃｌａｉｒ"}},
+	} {
+		var hl []string
+		for _, s := range root.SubstringSearch([]byte(tt.pattern)) {
+			for _, o := range s.Offsets {
+				hl = append(hl, string(s.Display[o[0]:o[1]]))
+			}
+		}
+		if !slices.Equal(hl, tt.want) {
+			t.Errorf("%q: got highlights %q, want %q", tt.pattern, hl, tt.want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package typeahead
 
+import "slices"
+
 // REFERENCES:
 // https://www.cs.upc.edu/~ps/downloads/tst/tst.html
 // http://hacktalks.blogspot.com/2012/03/implementing-auto-complete-with-ternary.html
@@ -22,10 +24,11 @@ package typeahead
 //         tree.Add("hi")
 //         tree.Add("car")
 //         fmt.Println("has hello:", tree.Contains("dobby"))
-//         result := tree.Search("he")
+//         result := tree.Search("he", 0)
 //         fmt.Println(result)
 //         fmt.Println(tree.Traverse())
-//         tree.NearSearch("dobbs")
+//         fmt.Println(tree.NearSearch("dobbs", 1))
+//         fmt.Println(tree.NearSearchEdit("helo", 1))
 // }
 
 type TernaryNode struct {
@@ -45,12 +48,16 @@ func NewTernaryNode(char rune, endword bool) *TernaryNode {
 
 type TernaryTree struct {
 	root *TernaryNode
+	size int
 }
 
 func NewTernaryTree() *TernaryTree { return &TernaryTree{} }
 
-// Add adds the item to the tree recursively.
+// Add adds the item to the tree recursively. The empty string is ignored.
 func (t *TernaryTree) Add(s string) {
+	if s == "" {
+		return
+	}
 	t.root = t.radd([]rune(s), 0, t.root)
 }
 
@@ -64,6 +71,9 @@ func (t *TernaryTree) radd(s []rune, pos int, node *TernaryNode) *TernaryNode {
 		node.right = t.radd(s, pos, node.right)
 	} else {
 		if pos+1 == len(s) {
+			if !node.endword {
+				t.size++
+			}
 			node.endword = true
 		} else {
 			node.center = t.radd(s, pos+1, node.center)
@@ -72,8 +82,16 @@ func (t *TernaryTree) radd(s []rune, pos int, node *TernaryNode) *TernaryNode {
 	return node
 }
 
+// Insert adds the key to the tree, see Add.
+func (t *TernaryTree) Insert(key string) {
+	t.Add(key)
+}
+
 func (t *TernaryTree) Contains(str string) bool {
 	r := []rune(str)
+	if len(r) == 0 {
+		return false
+	}
 	result := traverse(t.root, r)
 	if result == nil {
 		return false
@@ -101,42 +119,184 @@ func traverse(node *TernaryNode, r []rune) *TernaryNode {
 	return node
 }
 
-// Search implements an autocomplete for ternary search tree.
-func (t *TernaryTree) Search(str string) (result []string) {
-	r := []rune(str)
-	node := traverse(t.root, r)
-	if node == nil {
-		return
+// Search returns up to limit words that start with the prefix, including the
+// prefix itself, in sorted order. A limit of zero or less returns every
+// completion, and an empty prefix every word.
+func (t *TernaryTree) Search(prefix string, limit int) (result []string) {
+	r := []rune(prefix)
+	node := t.root
+	if len(r) > 0 {
+		node = traverse(t.root, r)
+		if node == nil {
+			return
+		}
+		if node.endword {
+			result = append(result, prefix)
+			if limit > 0 && len(result) >= limit {
+				return
+			}
+		}
+		node = node.center
 	}
-	t.dfs(node.center, append(r, node.center.char), &result)
+	t.collect(node, r, limit, &result)
 	return
 }
 
-func (t *TernaryTree) Traverse() (result []string) {
-	match := []rune{t.root.char}
-	t.dfs(t.root, match, &result)
-	return
+// Complete returns up to limit words that start with the prefix, see Search.
+func (t *TernaryTree) Complete(prefix string, limit int) []string {
+	return t.Search(prefix, limit)
+}
+
+// Len returns the number of words in the tree.
+func (t *TernaryTree) Len() int {
+	return t.size
+}
+
+// Delete removes the key from the tree, and the nodes that no longer lead to
+// a word. It reports whether the key was found.
+func (t *TernaryTree) Delete(key string) bool {
+	if key == "" {
+		return false
+	}
+	var ok bool
+	t.root = t.rdelete([]rune(key), 0, t.root, &ok)
+	if ok {
+		t.size--
+	}
+	return ok
+}
+
+func (t *TernaryTree) rdelete(s []rune, pos int, node *TernaryNode, ok *bool) *TernaryNode {
+	if node == nil {
+		return nil
+	}
+	switch {
+	case s[pos] < node.char:
+		node.left = t.rdelete(s, pos, node.left, ok)
+	case s[pos] > node.char:
+		node.right = t.rdelete(s, pos, node.right, ok)
+	case pos+1 == len(s):
+		*ok = node.endword
+		node.endword = false
+	default:
+		node.center = t.rdelete(s, pos+1, node.center, ok)
+	}
+	// Every node ends a word or leads to one through its center.
+	if node.endword || node.center != nil {
+		return node
+	}
+	return unlinkTernary(node)
+}
+
+// unlinkTernary removes the node from the binary search tree of its siblings,
+// and returns the node that takes its place.
+func unlinkTernary(node *TernaryNode) *TernaryNode {
+	switch {
+	case node.left == nil:
+		return node.right
+	case node.right == nil:
+		return node.left
+	}
+	// The smallest of the right siblings takes the place of the node.
+	parent, next := node, node.right
+	for next.left != nil {
+		parent, next = next, next.left
+	}
+	if parent != node {
+		parent.left = next.right
+		next.right = node.right
+	}
+	next.left = node.left
+	return next
 }
 
-// dfs performs depth-first-search for ternary tree.
-func (t *TernaryTree) dfs(node *TernaryNode, match []rune, result *[]string) {
+// collect appends the words below the node to result in sorted order, and
+// reports whether the limit has not been reached yet.
+func (t *TernaryTree) collect(node *TernaryNode, match []rune, limit int, result *[]string) bool {
+	if node == nil {
+		return true
+	}
+	if !t.collect(node.left, match, limit, result) {
+		return false
+	}
+	next := append(match[:len(match):len(match)], node.char)
 	if node.endword {
-		*result = append(*result, string(match))
+		*result = append(*result, string(next))
+		if limit > 0 && len(*result) >= limit {
+			return false
+		}
 	}
-	if node.center == nil && node.left == nil && node.right == nil {
+	return t.collect(node.center, next, limit, result) && t.collect(node.right, match, limit, result)
+}
+
+// Traverse returns every word of the tree in sorted order.
+func (t *TernaryTree) Traverse() []string {
+	return t.Search("", 0)
+}
+
+// NearSearch returns the words that have the same length as str and differ
+// from it in at most d characters, i.e. within Hamming distance d.
+func (t *TernaryTree) NearSearch(str string, d int) (result []string) {
+	r := []rune(str)
+	if len(r) == 0 {
 		return
 	}
-	if node.center != nil {
-		t.dfs(node.center, append(match, node.center.char), result)
+	t.nearSearch(t.root, r, 0, d, nil, &result)
+	return
+}
+
+// nearSearch only visits the left and right siblings when there are edits to
+// spare, or when the searched character lies on that side.
+func (t *TernaryTree) nearSearch(node *TernaryNode, s []rune, pos, d int, match []rune, result *[]string) {
+	if node == nil || d < 0 {
+		return
 	}
-	if node.right != nil {
-		match[len(match)-1] = node.right.char
-		t.dfs(node.right, match, result)
+	if d > 0 || s[pos] < node.char {
+		t.nearSearch(node.left, s, pos, d, match, result)
 	}
-	if node.left != nil {
-		match[len(match)-1] = node.left.char
-		t.dfs(node.left, match, result)
+	cost := 0
+	if s[pos] != node.char {
+		cost = 1
+	}
+	next := append(match[:len(match):len(match)], node.char)
+	if pos+1 == len(s) {
+		if node.endword && d-cost >= 0 {
+			*result = append(*result, string(next))
+		}
+	} else {
+		t.nearSearch(node.center, s, pos+1, d-cost, next, result)
+	}
+	if d > 0 || s[pos] > node.char {
+		t.nearSearch(node.right, s, pos, d, match, result)
 	}
 }
 
-// TODO: Implement nearest neighbour with hamming distance.
+// NearSearchEdit returns the words within Levenshtein distance d of str.
+func (t *TernaryTree) NearSearchEdit(str string, d int) (result []string) {
+	r := []rune(str)
+	row := make([]int, len(r)+1)
+	for i := range row {
+		row[i] = i
+	}
+	t.nearSearchEdit(t.root, r, d, row, nil, &result)
+	return
+}
+
+// nearSearchEdit carries the Levenshtein row of the path leading to the node.
+// The siblings share that row, while the center child is skipped once every
+// entry of the extended row exceeds d.
+func (t *TernaryTree) nearSearchEdit(node *TernaryNode, s []rune, d int, row []int, match []rune, result *[]string) {
+	if node == nil {
+		return
+	}
+	t.nearSearchEdit(node.left, s, d, row, match, result)
+	next := append(match[:len(match):len(match)], node.char)
+	curr := nextRow(row, s, node.char)
+	if node.endword && curr[len(s)] <= d {
+		*result = append(*result, string(next))
+	}
+	if slices.Min(curr) <= d {
+		t.nearSearchEdit(node.center, s, d, curr, next, result)
+	}
+	t.nearSearchEdit(node.right, s, d, row, match, result)
+}
@@ -0,0 +1,130 @@
+package typeahead
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+)
+
+func TestTernaryTreeNearSearch(t *testing.T) {
+	tree := NewTernaryTree()
+	for _, w := range []string{"hello", "dobby", "debby", "dobbs", "hells", "helrs", "helsinki", "hobby", "sell", "hallo", "anglo", "hi", "car"} {
+		tree.Add(w)
+	}
+	tests := []struct {
+		search string
+		d      int
+		want   []string
+	}{
+		{"dobbs", 0, []string{"dobbs"}},
+		{"dobbs", 1, []string{"dobbs", "dobby"}},
+		{"dobbs", 2, []string{"debby", "dobbs", "dobby", "hobby"}},
+		{"hello", 1, []string{"hallo", "hello", "hells"}},
+		{"xyz", 1, nil},
+	}
+	for _, tt := range tests {
+		if got := tree.NearSearch(tt.search, tt.d); !slices.Equal(got, tt.want) {
+			t.Errorf("NearSearch(%q, %d) = %q, want %q", tt.search, tt.d, got, tt.want)
+		}
+	}
+
+	edits := []struct {
+		search string
+		d      int
+		want   []string
+	}{
+		{"helo", 1, []string{"hello"}},
+		{"hel", 2, []string{"hello", "hells", "helrs", "hi", "sell"}},
+		{"cars", 1, []string{"car"}},
+	}
+	for _, tt := range edits {
+		if got := tree.NearSearchEdit(tt.search, tt.d); !slices.Equal(got, tt.want) {
+			t.Errorf("NearSearchEdit(%q, %d) = %q, want %q", tt.search, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestTernaryTree(t *testing.T) {
+	f := func(in, del [][]byte, prefix []byte, limit uint8) bool {
+		tree := NewTernaryTree()
+		oracle := make(map[string]bool)
+		for _, w := range runeWords(in) {
+			tree.Insert(string(w))
+			if len(w) > 0 {
+				oracle[string(w)] = true
+			}
+		}
+		for _, w := range runeWords(del) {
+			if tree.Delete(string(w)) != oracle[string(w)] {
+				return false
+			}
+			delete(oracle, string(w))
+		}
+		checkTernary(t, tree.root)
+		all := slices.Sorted(maps.Keys(oracle))
+		if tree.Len() != len(oracle) || !slices.Equal(tree.Traverse(), all) {
+			return false
+		}
+		for _, w := range append(runeWords(in), runeWords(del)...) {
+			if tree.Contains(string(w)) != oracle[string(w)] {
+				return false
+			}
+		}
+
+		p := string(runeWords([][]byte{prefix})[0])
+		var want []string
+		for _, w := range all {
+			if strings.HasPrefix(w, p) {
+				want = append(want, w)
+			}
+		}
+		if n := int(limit % 8); n > 0 {
+			want = want[:min(len(want), n)]
+		}
+		got := tree.Search(p, int(limit%8))
+		if !slices.Equal(got, want) {
+			t.Logf("Search(%q, %d) = %q, want %q", p, limit%8, got, want)
+			return false
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkTernary checks that every node ends a word or leads to one.
+func checkTernary(t *testing.T, node *TernaryNode) {
+	t.Helper()
+	if node == nil {
+		return
+	}
+	if !node.endword && node.center == nil {
+		t.Fatalf("node %q leads to no word", node.char)
+	}
+	checkTernary(t, node.left)
+	checkTernary(t, node.center)
+	checkTernary(t, node.right)
+}
+
+func TestTernaryTreeSearch(t *testing.T) {
+	tree := NewTernaryTree()
+	if got := tree.Traverse(); got != nil {
+		t.Fatalf("got %q from an empty tree", got)
+	}
+	for _, w := range []string{"car", "cart", "care", "cat", "dog"} {
+		tree.Add(w)
+	}
+	// "cart" is a word without any longer completion.
+	if got := tree.Search("cart", 0); !slices.Equal(got, []string{"cart"}) {
+		t.Fatalf("got %q", got)
+	}
+	if got := tree.Search("ca", 3); !slices.Equal(got, []string{"car", "care", "cart"}) {
+		t.Fatalf("got %q", got)
+	}
+	if got := tree.Traverse(); !slices.Equal(got, []string{"car", "care", "cart", "cat", "dog"}) {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,129 @@
+package typeahead
+
+import (
+	"bytes"
+	"container/heap"
+	"math"
+	"time"
+)
+
+// Suggestion is a ranked completion.
+type Suggestion[V any] struct {
+	Key []byte
+	// Display is the form of the key to show, see Edge.Display. It is Key
+	// when the key was not changed by a normalizer.
+	Display []byte
+	Count   int
+	Value   V
+	// Distance is the number of edits between the query and the completion,
+	// and is only set by the fuzzy lookups.
+	Distance int
+	// Score is the decayed score of the completion, and is only set by the
+	// lookups that rank by it.
+	Score float64
+	// Offsets are the byte ranges [start, end) of the matches of the pattern
+	// in Display, and are only set by SubstringSearch.
+	Offsets [][2]int
+}
+
+// TopK returns the k most frequent words that start with the given prefix,
+// including the prefix itself when it is a word. Ties are broken by the
+// lexicographic order of the keys.
+func (r *Root[V]) TopK(prefix []byte, k int) []Suggestion[V] {
+	return topK(&(r.Node), r.normalizeQuery(prefix), k, func(e *Edge[V]) (float64, float64) {
+		return float64(e.Frequency()), float64(e.best())
+	}, nil, nil)
+}
+
+// TopKDecayed returns the k words with the highest decayed score that start
+// with the given prefix, see Root.HalfLife. The scores of the suggestions are
+// given as of the time at.
+func (r *Root[V]) TopKDecayed(prefix []byte, k int, at time.Time) []Suggestion[V] {
+	return topK(&(r.Node), r.normalizeQuery(prefix), k, func(e *Edge[V]) (float64, float64) {
+		rank := e.rank(r.HalfLife)
+		return rank, max(rank, e.Node.MaxRank)
+	}, func(e *Edge[V]) float64 {
+		return e.Decayed(r.HalfLife, at)
+	}, nil)
+}
+
+// ranker returns the rank of the word that ends at the edge, and an upper
+// bound of the ranks of the words in the subtree of the edge.
+type ranker[V any] func(e *Edge[V]) (word, bound float64)
+
+// candidate is either a word waiting to be emitted, or an edge whose subtree
+// has not been expanded yet. For the latter, rank is the upper bound of the
+// ranks in the subtree. E refers to the edge.
+type candidate[E any] struct {
+	key  []byte
+	rank float64
+	edge E
+	word bool
+}
+
+type candidates[E any] []candidate[E]
+
+func (c candidates[E]) Len() int { return len(c) }
+
+func (c candidates[E]) Less(i, j int) bool {
+	if c[i].rank != c[j].rank {
+		return c[i].rank > c[j].rank
+	}
+	return bytes.Compare(c[i].key, c[j].key) < 0
+}
+
+func (c candidates[E]) Swap(i, j int) { c[i], c[j] = c[j], c[i] }
+
+func (c *candidates[E]) Push(x any) { *c = append(*c, x.(candidate[E])) }
+
+func (c *candidates[E]) Pop() any {
+	old := *c
+	n := len(old)
+	x := old[n-1]
+	*c = old[:n-1]
+	return x
+}
+
+// topK performs a best-first search over the subtrees that match the prefix.
+// Every word in a subtree has a key that sorts after the key of the subtree
+// and a rank no higher than its bound, so the words are popped in rank order
+// and the search stops as soon as k of them are found. The score of the
+// suggestions is set by score, when given. When after is given, only the words
+// for which it reports true are returned.
+func topK[V any](root *Node[V], prefix []byte, k int, rank ranker[V], score func(e *Edge[V]) float64, after func(rank float64, key []byte) bool) []Suggestion[V] {
+	if root == nil || k <= 0 {
+		return nil
+	}
+	path, edges := seek(root, prefix)
+	var pq candidates[*Edge[V]]
+	push := func(path []byte, edges []Edge[V]) {
+		for i := range edges {
+			key := make([]byte, 0, len(path)+len(edges[i].Key))
+			key = append(append(key, path...), edges[i].Key...)
+			_, bound := rank(&edges[i])
+			heap.Push(&pq, candidate[*Edge[V]]{key: key, rank: bound, edge: &edges[i]})
+		}
+	}
+	push(path, edges)
+
+	var out []Suggestion[V]
+	for pq.Len() > 0 && len(out) < k {
+		c := heap.Pop(&pq).(candidate[*Edge[V]])
+		if c.word {
+			if after != nil && !after(c.rank, c.key) {
+				continue
+			}
+			s := Suggestion[V]{Key: c.key, Display: c.edge.display(c.key), Count: c.edge.Frequency(), Value: c.edge.Value}
+			if score != nil {
+				s.Score = score(c.edge)
+			}
+			out = append(out, s)
+			continue
+		}
+		if word, _ := rank(c.edge); c.edge.Endword && !math.IsInf(word, -1) {
+			heap.Push(&pq, candidate[*Edge[V]]{key: c.key, rank: word, edge: c.edge, word: true})
+		}
+		push(c.key, c.edge.Node.Edges)
+	}
+	return out
+}
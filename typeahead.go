@@ -2,93 +2,291 @@ package typeahead
 
 import (
 	"bytes"
+	"math"
+	"slices"
+	"time"
+	"unicode/utf8"
 )
 
-// Root represents the root of the radix tree.
-type Root struct {
-	Node Node
+// Root represents the root of the radix tree. V is the type of the values
+// stored with the keys.
+type Root[V any] struct {
+	Node Node[V]
+	// HalfLife is the time it takes for the score of a word to halve, so
+	// that recent queries outrank the ones that were popular long ago. Zero
+	// disables the decay. It must be set before any key is inserted.
+	HalfLife time.Duration
+	// Codec encodes the values in the snapshots of the tree. It defaults to
+	// JSONCodec.
+	Codec Codec[V]
+	// Normalizer maps the keys before they are inserted or looked up, e.g.
+	// Standard. The key that a word was first inserted by is kept as its
+	// display form when it differs, see Edge.Display. Nil keeps the keys as
+	// they are. It must be set before any key is inserted. A prefix that
+	// ends in whitespace keeps a trailing space after it is normalized, so
+	// that it only completes the words that continue past it.
+	Normalizer Normalizer
 }
 
-// New returns a new tree.
-func New() *Root {
-	return &Root{
-		Node: NewNode(),
+// New returns a new tree that stores untyped values.
+func New() *Root[any] {
+	return NewOf[any]()
+}
+
+// NewOf returns a new tree that stores values of type V.
+func NewOf[V any]() *Root[V] {
+	return &Root[V]{
+		Node: NewNode[V](),
+	}
+}
+
+// Insert adds a key value pair into the tree. Inserting an existing key
+// increments its count and replaces its value.
+func (r *Root[V]) Insert(key []byte, value V) {
+	r.insertAt(key, value, 1, time.Now())
+}
+
+// Increment raises the frequency of the key by n, adding the key with the zero
+// value when it is missing. The stored value is kept.
+func (r *Root[V]) Increment(key []byte, n int) {
+	r.IncrementAt(key, n, time.Now())
+}
+
+// IncrementAt raises the frequency of the key by n like Increment, with the
+// score updated as of the given time, e.g. the time of a logged query.
+func (r *Root[V]) IncrementAt(key []byte, n int, at time.Time) {
+	if n <= 0 {
+		return
 	}
+	value, _, _ := r.Get(key)
+	r.insertAt(key, value, n, at)
 }
 
-// Insert adds a key value pair into the tree.
-func (r *Root) Insert(key []byte, value any) {
-	insert(&(r.Node), key, value)
+// insertAt adds the key with the given value, raising its frequency by n and
+// its score as of the given time.
+func (r *Root[V]) insertAt(key []byte, value V, n int, at time.Time) {
+	norm := r.normalize(key)
+	_, found := lookup(&(r.Node), norm)
+	insert(&(r.Node), bytes.Clone(norm), value, n)
+	if edge := edgeAt(&(r.Node), norm); edge != nil && !found && !bytes.Equal(norm, key) {
+		edge.Display = bytes.Clone(key)
+	}
+	rescore(&(r.Node), norm, float64(n), at, r.HalfLife)
+}
+
+// Get returns the value and the frequency stored for the exact key.
+func (r *Root[V]) Get(key []byte) (value V, count int, ok bool) {
+	edge, ok := lookup(&(r.Node), r.normalize(key))
+	if !ok {
+		return value, 0, false
+	}
+	return edge.Value, edge.Frequency(), true
+}
+
+// Contains reports whether the exact key is stored in the tree.
+func (r *Root[V]) Contains(key []byte) bool {
+	_, ok := lookup(&(r.Node), r.normalize(key))
+	return ok
+}
+
+// Delete removes the key from the tree. It reports whether the key was found.
+func (r *Root[V]) Delete(key []byte) bool {
+	key = r.normalize(key)
+	ok := remove(&(r.Node), key, math.MaxInt) > 0
+	rescore(&(r.Node), key, 0, time.Now(), r.HalfLife)
+	return ok
+}
+
+// Decrement lowers the frequency of the key by n. The key is removed once its
+// frequency drops to zero. It reports whether the key was found.
+func (r *Root[V]) Decrement(key []byte, n int) bool {
+	return r.decrementAt(key, n, time.Now())
+}
+
+// decrementAt lowers the frequency of the key by n, with the score updated as
+// of the given time.
+func (r *Root[V]) decrementAt(key []byte, n int, at time.Time) bool {
+	if n <= 0 {
+		return false
+	}
+	key = r.normalize(key)
+	ok := remove(&(r.Node), key, n) > 0
+	rescore(&(r.Node), key, -float64(n), at, r.HalfLife)
+	return ok
+}
+
+// Len returns the number of distinct keys in the tree.
+func (r *Root[V]) Len() int {
+	var n int
+	walk(r.Node.Edges, nil, func(_ []byte, edge Edge[V]) bool {
+		if edge.Endword {
+			n++
+		}
+		return true
+	})
+	return n
 }
 
 // Find searches for the edge of the node that matches the given prefix.
-func (r *Root) Find(key []byte) map[string]Edge {
-	return find(&(r.Node), key)
+func (r *Root[V]) Find(key []byte) map[string]Edge[V] {
+	return find(&(r.Node), r.normalizeQuery(key))
 }
 
-func (r *Root) FindRecursive(key []byte) [][]byte {
-	return findRecursive(&(r.Node), key)
+// FindRecursive returns the keys of all the words that complete the given
+// prefix.
+func (r *Root[V]) FindRecursive(key []byte) [][]byte {
+	return findRecursive(&(r.Node), r.normalizeQuery(key))
 }
 
-func insert(root *Node, key []byte, value any) {
+// insert adds the key with the given value, raising its frequency by n.
+func insert[V any](root *Node[V], key []byte, value V, n int) {
 	if root == nil || len(key) == 0 {
 		return
 	}
-	var p, pos int
-	for i, child := range root.Edges {
-		if len(child.Key) == 0 || child.Key[0] != key[0] {
-			continue
-		}
-		p = sharedPrefix(child.Key, key)
-		pos = i
-		break
-	}
-	if p == 0 {
+	defer root.updateMax()
+	pos := root.index(key)
+	if pos == -1 {
 		edge := NewEdge(key, value)
+		edge.Count = n
 		edge.Endword = true
-		root.Edges = append(root.Edges, edge)
+		root.insertEdge(edge)
 		return
 	}
+	p := sharedPrefix(root.Edges[pos].Key, key)
 	currKey := root.Edges[pos].Key
 	if bytes.Equal(currKey, key) {
-		root.Edges[pos].Count++
+		root.Edges[pos].Count += n
 		root.Edges[pos].Endword = true
+		root.Edges[pos].Value = value
 		return
 	}
 	if len(currKey) == p {
-		root.Edges[pos].Count++
+		root.Edges[pos].Count += n
 		node := root.Edges[pos].Node
-		insert(&node, key[p:], value)
+		insert(&node, key[p:], value, n)
 		root.Edges[pos].Node = node
 		return
 	}
-	split(root, key, p, pos)
+	split(root, key, value, n, p, pos)
 }
 
-func split(root *Node, key []byte, p, pos int) {
-	var rem int
+func split[V any](root *Node[V], key []byte, value V, n, p, pos int) {
 	edge := root.Edges[pos]
-	for k, v := range root.Edges {
-		if bytes.Equal(v.Key, edge.Key) {
-			rem = k
-			break
-		}
-	}
 	// Pop the old edge.
-	root.Edges = append(root.Edges[:rem], root.Edges[rem+1:]...)
+	root.deleteEdge(pos)
 	prefix, left, right := edge.Key[:p], edge.Key[p:], key[p:]
 
-	newEdge := NewEdge(prefix, nil)
-	newEdge.Count += edge.Count
+	var zero V
+	newEdge := NewEdge(prefix, zero)
+	newEdge.Count = n + edge.Count
 
 	edge.Key = left
 
-	insert(&(newEdge.Node), right, nil)
-	newEdge.Node.Edges = append(newEdge.Node.Edges, edge)
-	root.Edges = append(root.Edges, newEdge)
+	// The inserted key is the prefix itself, so the new edge ends a word.
+	if len(right) == 0 {
+		newEdge.Endword = true
+		newEdge.Value = value
+	}
+	insert(&(newEdge.Node), right, value, n)
+	newEdge.Node.insertEdge(edge)
+	newEdge.Node.updateMax()
+	root.insertEdge(newEdge)
 }
 
-func complete(root *Node, orikey []byte) [][]byte {
+// remove lowers the frequency of the key by at most n and returns the amount
+// that was subtracted. Edges left without a word are dropped, and edges left
+// with a single child are merged with it, which undoes a split.
+func remove[V any](root *Node[V], key []byte, n int) int {
+	if root == nil || len(key) == 0 {
+		return 0
+	}
+	pos := root.index(key)
+	if pos == -1 || !bytes.HasPrefix(key, root.Edges[pos].Key) {
+		return 0
+	}
+	edge := &root.Edges[pos]
+	var d int
+	if len(edge.Key) == len(key) {
+		if !edge.Endword {
+			return 0
+		}
+		freq := edge.Frequency()
+		d = min(n, freq)
+		if d == freq {
+			var zero V
+			edge.Endword = false
+			edge.Value = zero
+			edge.Display = nil
+			edge.Score, edge.Updated = 0, time.Time{}
+		}
+	} else {
+		d = remove(&(edge.Node), key[len(edge.Key):], n)
+	}
+	if d == 0 {
+		return 0
+	}
+	edge.Count -= d
+	if !edge.Endword {
+		switch len(edge.Node.Edges) {
+		case 0:
+			root.deleteEdge(pos)
+		case 1:
+			merged := merge(*edge)
+			root.deleteEdge(pos)
+			root.insertEdge(merged)
+		}
+	}
+	root.updateMax()
+	return d
+}
+
+// rescore adds delta to the decayed score of the key as of the given time, and
+// recomputes the cached ranks of the nodes along the path of the key.
+func rescore[V any](root *Node[V], key []byte, delta float64, at time.Time, halfLife time.Duration) {
+	nodes := []*Node[V]{root}
+	for n := root; len(key) > 0; {
+		next := n.edge(key)
+		if next == nil || !bytes.HasPrefix(key, next.Key) {
+			break
+		}
+		key = key[len(next.Key):]
+		if len(key) == 0 && next.Endword && delta != 0 {
+			next.Score = max(0, next.Decayed(halfLife, at)+delta)
+			next.Updated = at
+		}
+		n = &(next.Node)
+		nodes = append(nodes, n)
+	}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		nodes[i].updateRank(halfLife)
+	}
+}
+
+// edgeAt returns the edge that ends the exact key, or nil.
+func edgeAt[V any](root *Node[V], key []byte) *Edge[V] {
+	for n := root; len(key) > 0; {
+		next := n.edge(key)
+		if next == nil || !bytes.HasPrefix(key, next.Key) {
+			return nil
+		}
+		key = key[len(next.Key):]
+		if len(key) == 0 {
+			return next
+		}
+		n = &(next.Node)
+	}
+	return nil
+}
+
+// merge joins an edge with its only child.
+func merge[V any](edge Edge[V]) Edge[V] {
+	child := edge.Node.Edges[0]
+	key := make([]byte, 0, len(edge.Key)+len(child.Key))
+	child.Key = append(append(key, edge.Key...), child.Key...)
+	return child
+}
+
+func complete[V any](root *Node[V], orikey []byte) [][]byte {
 	key := make([]byte, len(orikey))
 	copy(key, orikey)
 	out := make([][]byte, 0)
@@ -103,94 +301,115 @@ func complete(root *Node, orikey []byte) [][]byte {
 	return out
 }
 
-func findRecursive(root *Node, key []byte) [][]byte {
-	if root == nil || len(key) == 0 {
+func findRecursive[V any](root *Node[V], key []byte) [][]byte {
+	if root == nil {
 		return nil
 	}
-	foundElements := 0
-	traverseNode := root
-	for traverseNode != nil && !traverseNode.IsLeaf() && foundElements < len(key) {
-		var nextEdge *Edge
-		for _, edge := range traverseNode.Edges {
-			if bytes.HasPrefix(key[foundElements:], edge.Key) {
-				nextEdge = &edge
-				break
-			}
-		}
-		if nextEdge == nil {
-			traverseNode = nil
-			break
-		}
-		foundElements += len(nextEdge.Key)
-		traverseNode = &(nextEdge.Node)
-	}
-	if traverseNode == nil || traverseNode.IsLeaf() {
-		return nil
+	prefix, edges := seek(root, key)
+	out := complete(&Node[V]{Edges: edges}, prefix)
+	// Only the proper completions are returned, so drop the key itself.
+	if i := slices.IndexFunc(out, func(k []byte) bool { return bytes.Equal(k, key) }); i != -1 {
+		out = slices.Delete(out, i, i+1)
 	}
-	return complete(traverseNode, key)
+	return out
 }
 
-func find(root *Node, in []byte) map[string]Edge {
-	if root == nil || len(in) == 0 {
+func find[V any](root *Node[V], in []byte) map[string]Edge[V] {
+	if root == nil {
 		return nil
 	}
-	result := make(map[string]Edge)
-
-	var stack []Edge
-	stack = append(stack, NewEdge(in, nil))
-	for len(stack) > 0 {
-		var key Edge
-		var foundKey []byte
-		key, stack = stack[0], stack[1:]
-		if edge, found := result[key.String()]; found && edge.Endword && edge.Node.IsLeaf() {
-			continue
+	result := make(map[string]Edge[V])
+	prefix, edges := seek(root, in)
+	walk(edges, prefix, func(key []byte, edge Edge[V]) bool {
+		if edge.Endword && !bytes.Equal(key, in) {
+			result[string(key)] = edge
 		}
-		// Need to make a copy of the byte.
-		foundKey = make([]byte, len(key.Key))
-		copy(foundKey, key.Key)
-		var foundElements int
-		traverseNode := root
-		for traverseNode != nil && !traverseNode.IsLeaf() && foundElements < len(foundKey) {
-			var nextEdge *Edge
-			for _, edge := range traverseNode.Edges {
-				if bytes.HasPrefix(foundKey[foundElements:], edge.Key) {
-					nextEdge = &edge
-					break
+		return true
+	})
+	return result
+}
+
+// seek walks down the tree along the given key. It returns the edges whose
+// subtrees hold every word that starts with key, together with the bytes
+// that lead up to those edges. The key may end in the middle of an edge, or
+// of a rune.
+func seek[V any](root *Node[V], key []byte) ([]byte, []Edge[V]) {
+	var found int
+	n := root
+	for found < len(key) {
+		rest := key[found:]
+		if !utf8.FullRune(rest) {
+			// The key ends in the middle of a rune, which is completed by
+			// every edge that starts with the rest of the key.
+			var out []Edge[V]
+			for _, edge := range n.Edges {
+				if bytes.HasPrefix(edge.Key, rest) {
+					out = append(out, edge)
 				}
 			}
-			if nextEdge == nil {
-				traverseNode = nil
-				break
+			if len(out) == 0 {
+				return nil, nil
 			}
-			foundElements += len(nextEdge.Key)
-			traverseNode = &(nextEdge.Node)
+			return key[:found], out
 		}
-		if traverseNode == nil || traverseNode.IsLeaf() {
-			continue
+		next := n.edge(rest)
+		if next == nil {
+			return nil, nil
 		}
-		for _, edge := range traverseNode.Edges {
-			func(key, edge Edge) {
-				out := append(foundKey, edge.Key...)
-				stack = append([]Edge{NewEdge(out, nil)}, stack...)
-				result[string(out)] = edge
-			}(key, edge)
+		if bytes.HasPrefix(next.Key, rest) {
+			return key[:found], []Edge[V]{*next}
 		}
+		if !bytes.HasPrefix(rest, next.Key) {
+			return nil, nil
+		}
+		found += len(next.Key)
+		n = &next.Node
+	}
+	return key[:found], n.Edges
+}
+
+// lookup returns the edge that ends the exact key.
+func lookup[V any](root *Node[V], key []byte) (Edge[V], bool) {
+	if root == nil {
+		return Edge[V]{}, false
+	}
+	edge := edgeAt(root, key)
+	if edge == nil || !edge.Endword {
+		return Edge[V]{}, false
 	}
+	return *edge, true
+}
 
-	for id, edge := range result {
-		if !edge.Endword {
-			delete(result, id)
+// walk visits the edges in pre-order, passing the full key of each edge. It
+// stops as soon as fn returns false, and reports whether it ran to the end.
+func walk[V any](edges []Edge[V], prefix []byte, fn func(key []byte, edge Edge[V]) bool) bool {
+	for _, edge := range edges {
+		key := make([]byte, 0, len(prefix)+len(edge.Key))
+		key = append(append(key, prefix...), edge.Key...)
+		if !fn(key, edge) || !walk(edge.Node.Edges, key, fn) {
+			return false
 		}
 	}
-	return result
+	return true
 }
 
+// sharedPrefix returns the length of the common prefix of s and t, which ends
+// between two characters, see head.
 func sharedPrefix(s, t []byte) int {
-	minLen := min(len(s), len(t))
-	for i := range minLen {
-		if s[i] != t[i] {
-			return i
+	var n int
+	for n < len(s) && n < len(t) {
+		if c := s[n]; c < utf8.RuneSelf {
+			if c != t[n] {
+				break
+			}
+			n++
+			continue
+		}
+		h := head(s[n:])
+		if !bytes.Equal(h, head(t[n:])) {
+			break
 		}
+		n += len(h)
 	}
-	return minLen
+	return n
 }
@@ -0,0 +1,157 @@
+package typeahead
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/quick"
+	"unicode/utf8"
+)
+
+// runeWords maps random bytes onto multi-byte runes that share their leading
+// bytes, so that the byte-wise prefixes of the words end in the middle of a
+// rune.
+func runeWords(in [][]byte) [][]byte {
+	alphabet := []string{"a", "é", "è", "€", "₤", "日"}
+	out := make([][]byte, len(in))
+	for i, b := range in {
+		var w []byte
+		for _, c := range b[:len(b)%5] {
+			w = append(w, alphabet[int(c)%len(alphabet)]...)
+		}
+		out[i] = w
+	}
+	return out
+}
+
+func TestRuneSplits(t *testing.T) {
+	f := func(in, del [][]byte, cut uint8) bool {
+		root := NewOf[int]()
+		freq := make(map[string]int)
+		for i, w := range runeWords(in) {
+			root.Insert(w, i)
+			if len(w) > 0 {
+				freq[string(w)]++
+			}
+		}
+		for _, w := range runeWords(del) {
+			root.Delete(w)
+			delete(freq, string(w))
+		}
+		checkNode(t, root.Node)
+		valid := walk(root.Node.Edges, nil, func(_ []byte, edge Edge[int]) bool {
+			return utf8.Valid(edge.Key)
+		})
+		if !valid || root.Len() != len(freq) {
+			return false
+		}
+
+		var x bytes.Buffer
+		if _, err := root.WriteIndex(&x); err != nil {
+			t.Fatal(err)
+		}
+		index, err := NewIndex[int](x.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Cut the words anywhere, including in the middle of a rune.
+		for _, w := range runeWords(in) {
+			prefix := w[:min(len(w), int(cut)%8)]
+			var want []string
+			for k := range freq {
+				if strings.HasPrefix(k, string(prefix)) && k != string(prefix) {
+					want = append(want, k)
+				}
+			}
+			slices.Sort(want)
+			for _, got := range [][][]byte{root.FindRecursive(prefix), index.FindRecursive(prefix)} {
+				keys := make([]string, len(got))
+				for i, k := range got {
+					keys[i] = string(k)
+				}
+				slices.Sort(keys)
+				if !slices.Equal(keys, want) {
+					t.Logf("prefix %q: got %q, want %q", prefix, keys, want)
+					return false
+				}
+			}
+			if got := root.TopK(prefix, len(freq)+1); len(got) != len(want)+min(freq[string(prefix)], 1) {
+				return false
+			}
+		}
+		for _, k := range slices.Sorted(maps.Keys(freq)) {
+			_, n1, ok1 := root.Get([]byte(k))
+			_, n2, ok2, err := index.Get([]byte(k))
+			if !ok1 || !ok2 || err != nil || n1 != freq[k] || n2 != freq[k] {
+				return false
+			}
+			// A key cut in the middle of a rune is not a word.
+			if r, size := utf8.DecodeLastRuneInString(k); size > 1 && r != utf8.RuneError {
+				if root.Contains([]byte(k[:len(k)-1])) || index.Contains([]byte(k[:len(k)-1])) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRuneSplitsInvalid(t *testing.T) {
+	root := NewOf[int]()
+	keys := []string{"caf\xc3", "café", "cafè", "caf\xc3a", "caf\xc3\xc3"}
+	for i, k := range keys {
+		root.Insert([]byte(k), i)
+	}
+	checkNode(t, root.Node)
+	for i, k := range keys {
+		if v, n, ok := root.Get([]byte(k)); !ok || n != 1 || v != i {
+			t.Fatalf("get %q: got %d %d %t", k, v, n, ok)
+		}
+	}
+	// The prefix ending in the middle of "é" and "è" matches every key.
+	if got := root.TopK([]byte("caf\xc3"), 10); len(got) != len(keys) {
+		t.Fatalf("got %d suggestions, want %d", len(got), len(keys))
+	}
+	if got := root.FindRecursive([]byte("caf\xc3")); len(got) != len(keys)-1 {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestSnapshotRebuild(t *testing.T) {
+	// A version 2 snapshot of "café" and "cafè", split in the middle of the
+	// runes.
+	root := NewOf[int]()
+	root.Node.Edges = []Edge[int]{{Key: []byte("caf\xc3"), Count: 3, Node: Node[int]{Edges: []Edge[int]{
+		{Key: []byte("\xa9"), Count: 2, Endword: true, Value: 1},
+		{Key: []byte("\xa8"), Count: 1, Endword: true, Value: 2},
+	}}}}
+	var buf bytes.Buffer
+	if _, err := root.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()[:buf.Len()-4]
+	b[len(snapshotMagic)] = 2
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(b, castagnoli))
+
+	got := NewOf[int]()
+	if _, err := got.ReadFrom(bytes.NewReader(b)); err != nil {
+		t.Fatal(err)
+	}
+	checkNode(t, got.Node)
+	for k, want := range map[string]int{"café": 2, "cafè": 1} {
+		if v, n, ok := got.Get([]byte(k)); !ok || n != want || v != 3-want {
+			t.Fatalf("get %q: got %d %d %t", k, v, n, ok)
+		}
+	}
+	if !walk(got.Node.Edges, nil, func(_ []byte, edge Edge[int]) bool { return utf8.Valid(edge.Key) }) {
+		t.Fatal("rebuilt tree splits a rune")
+	}
+}
@@ -0,0 +1,448 @@
+package typeahead
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The write-ahead log format is made of:
+//
+//	header   magic "TAWL", version byte, id of the snapshot the log applies
+//	         to as uint64, little-endian
+//	record   uvarint payload length, payload, CRC-32C of the payload,
+//	         little-endian
+//	payload  op byte, time as unix nanoseconds varint, uvarint n,
+//	         uvarint key length, key, [value]
+//
+// The id of a snapshot is made of its size and checksum, and is zero when
+// there is no snapshot. A log whose id does not match the snapshot was
+// already folded into it, and is discarded.
+const (
+	logMagic   = "TAWL"
+	logVersion = 1
+	logHeader  = len(logMagic) + 1 + 8
+
+	// maxRecord bounds the payload of a record, so that a torn length does
+	// not allocate a huge buffer.
+	maxRecord = 1 << 30
+)
+
+// MaxCount is the largest count that a single write of a Durable may add or
+// remove, and the largest that the log replays.
+const MaxCount = math.MaxInt32
+
+// ErrCount is returned for a count above MaxCount.
+var ErrCount = errors.New("typeahead: count out of range")
+
+// The operations in the write-ahead log.
+const (
+	opInsert = iota + 1
+	opIncrement
+	opDelete
+	opDecrement
+)
+
+// Durable is a concurrent tree whose writes survive a crash. The tree is kept
+// in a snapshot, and every write is appended to a write-ahead log next to it
+// before it is applied. The log is replayed on top of the snapshot when the
+// tree is opened, and folded into a new snapshot by Compact.
+//
+// The writes reach the operating system before they return, so they survive
+// the process crashing, but they are only synced to the disk by Sync and
+// Compact.
+type Durable[V any] struct {
+	// mu serialises the writers, so that the log and the tree see the writes
+	// in the same order.
+	mu    sync.Mutex
+	path  string
+	tree  *Concurrent[V]
+	codec Codec[V]
+	log   *os.File
+	// size is the length of the log up to the last complete record.
+	size int64
+	// err is set when the log could not be restored after a failed write,
+	// or could not be replaced after a new snapshot was saved. The writes
+	// fail until Compact succeeds.
+	err error
+}
+
+// OpenDurable opens the tree stored in the snapshot at the path, and replays
+// the write-ahead log at path+".wal" on top of it. The snapshot is read into
+// r, which may be nil; its Codec encodes the values in the snapshot and the
+// log, and its HalfLife is used when there is no snapshot yet. A record that
+// was torn by a crash ends the log, and is dropped.
+func OpenDurable[V any](path string, r *Root[V]) (*Durable[V], error) {
+	if r == nil {
+		r = NewOf[V]()
+	}
+	id, err := load(path, r)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	d := &Durable[V]{
+		path:  path,
+		tree:  NewConcurrent[V](),
+		codec: r.codec(),
+	}
+
+	f, err := os.OpenFile(d.logPath(), os.O_RDWR, 0)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, err
+	default:
+		size, err := replay(f, r, id)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", d.logPath(), err)
+		}
+		// Drop the torn record, so that the next writes follow the last
+		// complete one.
+		if size > 0 {
+			if err := f.Truncate(size); err != nil {
+				f.Close()
+				return nil, err
+			}
+			if _, err := f.Seek(size, io.SeekStart); err != nil {
+				f.Close()
+				return nil, err
+			}
+			d.log, d.size = f, size
+		} else {
+			f.Close()
+		}
+	}
+	if d.log == nil {
+		if err := d.reset(id); err != nil {
+			return nil, err
+		}
+	}
+	d.tree.Store(r)
+	return d, nil
+}
+
+// Load returns the current snapshot of the tree for reading, see
+// Concurrent.Load.
+func (d *Durable[V]) Load() *Root[V] {
+	return d.tree.Load()
+}
+
+// Insert adds a key value pair into the tree, see Root.Insert.
+func (d *Durable[V]) Insert(key []byte, value V) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(key) == 0 {
+		return nil
+	}
+	at := time.Now()
+	if err := d.append(opInsert, key, 1, at, value); err != nil {
+		return err
+	}
+	d.tree.update(key, func(r *Root[V]) { r.insertAt(key, value, 1, at) })
+	return nil
+}
+
+// Increment raises the frequency of the key by n, see Root.Increment. It
+// returns ErrCount when n is above MaxCount.
+func (d *Durable[V]) Increment(key []byte, n int) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(key) == 0 || n <= 0 {
+		return nil
+	}
+	at := time.Now()
+	var zero V
+	if err := d.append(opIncrement, key, n, at, zero); err != nil {
+		return err
+	}
+	d.tree.update(key, func(r *Root[V]) { r.IncrementAt(key, n, at) })
+	return nil
+}
+
+// Delete removes the key from the tree, see Root.Delete.
+func (d *Durable[V]) Delete(key []byte) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.tree.Contains(key) {
+		return false, nil
+	}
+	var zero V
+	if err := d.append(opDelete, key, 0, time.Now(), zero); err != nil {
+		return false, err
+	}
+	return d.tree.Delete(key), nil
+}
+
+// Decrement lowers the frequency of the key by n, see Root.Decrement. It
+// returns ErrCount when n is above MaxCount.
+func (d *Durable[V]) Decrement(key []byte, n int) (ok bool, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if n > MaxCount {
+		return false, ErrCount
+	}
+	if n <= 0 || !d.tree.Contains(key) {
+		return false, nil
+	}
+	at := time.Now()
+	var zero V
+	if err := d.append(opDecrement, key, n, at, zero); err != nil {
+		return false, err
+	}
+	d.tree.update(key, func(r *Root[V]) { ok = r.decrementAt(key, n, at) })
+	return ok, nil
+}
+
+// Sync commits the log to the disk.
+func (d *Durable[V]) Sync() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.log.Sync()
+}
+
+// Compact saves a new snapshot of the tree and starts an empty log. The
+// writes wait for the snapshot to be saved, while the reads go on. It also
+// recovers from a failed write or compaction, as the new snapshot holds every
+// write that was applied.
+func (d *Durable[V]) Compact() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.log == nil {
+		return os.ErrClosed
+	}
+	if err := Save(d.path, d.tree.Load()); err != nil {
+		return err
+	}
+	// A crash from here on leaves the old log behind, which no longer
+	// matches the snapshot and is discarded when the tree is opened. The
+	// writes must not go to it either, so they fail until a new log is in
+	// place.
+	old := d.log
+	if err := d.restart(); err != nil {
+		if d.log == old {
+			d.err = fmt.Errorf("typeahead: starting a new log: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// restart starts an empty log for the snapshot at the path.
+func (d *Durable[V]) restart() error {
+	f, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	id, err := snapshotID(f, fi.Size())
+	if err != nil {
+		return err
+	}
+	return d.reset(id)
+}
+
+// Close closes the log. The writes that were not compacted are replayed when
+// the tree is opened again.
+func (d *Durable[V]) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.log == nil {
+		return nil
+	}
+	err := d.log.Close()
+	d.log = nil
+	return err
+}
+
+func (d *Durable[V]) logPath() string {
+	return d.path + ".wal"
+}
+
+// reset replaces the log with an empty one for the snapshot with the given
+// id. Like Save, it renames a synced temporary file over the log.
+func (d *Durable[V]) reset(id uint64) (err error) {
+	dir, base := filepath.Split(d.logPath())
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Once renamed, the new log is in use even if the directory could
+		// not be synced.
+		if err != nil && d.log != f {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	header := append([]byte(logMagic), logVersion)
+	header = binary.LittleEndian.AppendUint64(header, id)
+	if _, err := f.Write(header); err != nil {
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), d.logPath()); err != nil {
+		return err
+	}
+	if d.log != nil {
+		d.log.Close()
+	}
+	d.log, d.size, d.err = f, int64(len(header)), nil
+	return syncDir(dir)
+}
+
+// append writes a record to the log. A record that was only partly written
+// is cut off again, so that it does not hide the records after it.
+func (d *Durable[V]) append(op byte, key []byte, n int, at time.Time, value V) error {
+	if d.err != nil {
+		return d.err
+	}
+	if d.log == nil {
+		return os.ErrClosed
+	}
+	if n > MaxCount {
+		return ErrCount
+	}
+	payload := []byte{op}
+	payload = binary.AppendVarint(payload, at.UnixNano())
+	payload = binary.AppendUvarint(payload, uint64(n))
+	payload = binary.AppendUvarint(payload, uint64(len(key)))
+	payload = append(payload, key...)
+	if op == opInsert && any(value) != nil {
+		b, err := d.codec.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("typeahead: encoding the value of %q: %w", key, err)
+		}
+		payload = append(payload, b...)
+	}
+
+	record := binary.AppendUvarint(nil, uint64(len(payload)))
+	record = append(record, payload...)
+	record = binary.LittleEndian.AppendUint32(record, crc32.Checksum(payload, castagnoli))
+	if _, err := d.log.Write(record); err != nil {
+		if terr := d.log.Truncate(d.size); terr != nil {
+			d.err = fmt.Errorf("typeahead: restoring the log: %w", terr)
+		} else if _, serr := d.log.Seek(d.size, io.SeekStart); serr != nil {
+			d.err = fmt.Errorf("typeahead: restoring the log: %w", serr)
+		}
+		return err
+	}
+	d.size += int64(len(record))
+	return nil
+}
+
+// replay applies the records of the log to r, unless the log belongs to
+// another snapshot than the one with the given id. It returns the length of
+// the log up to the last complete record, or zero when the log is discarded.
+func replay[V any](f *os.File, r *Root[V], id uint64) (int64, error) {
+	br := bufio.NewReader(f)
+	header := make([]byte, logHeader)
+	if _, err := io.ReadFull(br, header); err != nil {
+		// The log is created in full before it is renamed into place, so
+		// it is only short when it was not written by OpenDurable.
+		return 0, fmt.Errorf("%w: short log header", ErrCorrupt)
+	}
+	if string(header[:len(logMagic)]) != logMagic {
+		return 0, fmt.Errorf("%w: bad magic %q", ErrCorrupt, header[:len(logMagic)])
+	}
+	if v := header[len(logMagic)]; v != logVersion {
+		return 0, fmt.Errorf("%w: got log version %d, want %d", ErrVersion, v, logVersion)
+	}
+	if binary.LittleEndian.Uint64(header[len(logMagic)+1:]) != id {
+		return 0, nil
+	}
+
+	size := int64(logHeader)
+	codec := r.codec()
+	for {
+		payload, n, ok := readRecord(br)
+		if !ok {
+			return size, nil
+		}
+		if err := apply(r, codec, payload); err != nil {
+			return 0, fmt.Errorf("record at byte %d: %w", size, err)
+		}
+		size += n
+	}
+}
+
+// readRecord reads the next record and returns its payload and its length. It
+// reports false at the end of the log, or when the record was torn.
+func readRecord(br *bufio.Reader) (payload []byte, n int64, ok bool) {
+	length, err := binary.ReadUvarint(br)
+	if err != nil || length > maxRecord {
+		return nil, 0, false
+	}
+	record := make([]byte, length+4)
+	if _, err := io.ReadFull(br, record); err != nil {
+		return nil, 0, false
+	}
+	payload = record[:length]
+	if crc32.Checksum(payload, castagnoli) != binary.LittleEndian.Uint32(record[length:]) {
+		return nil, 0, false
+	}
+	return payload, int64(len(binary.AppendUvarint(nil, length)) + len(record)), true
+}
+
+// apply performs the operation of a record on r.
+func apply[V any](r *Root[V], codec Codec[V], payload []byte) error {
+	br := bytes.NewReader(payload)
+	op, _ := br.ReadByte()
+	at, err := binary.ReadVarint(br)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	n, err := binary.ReadUvarint(br)
+	if err != nil || n > MaxCount {
+		return fmt.Errorf("%w: invalid count", ErrCorrupt)
+	}
+	length, err := binary.ReadUvarint(br)
+	if err != nil || length == 0 || length > uint64(br.Len()) {
+		return fmt.Errorf("%w: invalid key length", ErrCorrupt)
+	}
+	key := make([]byte, length)
+	br.Read(key)
+	rest := payload[len(payload)-br.Len():]
+
+	switch op {
+	case opInsert:
+		var value V
+		if len(rest) > 0 {
+			if value, err = codec.Unmarshal(rest); err != nil {
+				return fmt.Errorf("%w: decoding the value of %q: %w", ErrCorrupt, key, err)
+			}
+		}
+		r.insertAt(key, value, 1, time.Unix(0, at))
+	case opIncrement:
+		r.IncrementAt(key, int(n), time.Unix(0, at))
+	case opDelete:
+		r.Delete(key)
+	case opDecrement:
+		r.decrementAt(key, int(n), time.Unix(0, at))
+	default:
+		return fmt.Errorf("%w: unknown op %d", ErrCorrupt, op)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package typeahead
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDurable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.snapshot")
+	open := func() *Durable[string] {
+		t.Helper()
+		r := NewOf[string]()
+		r.HalfLife = time.Hour
+		d, err := OpenDurable(path, r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	check := func(d *Durable[string], want map[string]int) {
+		t.Helper()
+		r := d.Load()
+		checkNode(t, r.Node)
+		if r.Len() != len(want) {
+			t.Fatalf("got %d keys, want %d", r.Len(), len(want))
+		}
+		for key, count := range want {
+			v, n, ok := r.Get([]byte(key))
+			if !ok || n != count || v != key+"!" {
+				t.Fatalf("get %q: got %q %d %t, want count %d", key, v, n, ok, count)
+			}
+		}
+	}
+
+	d := open()
+	for _, w := range []string{"alex", "alexander", "alexis", "banana", "band"} {
+		if err := d.Insert([]byte(w), w+"!"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Increment([]byte("alexis"), 4)
+	d.Decrement([]byte("alex"), 1)
+	d.Delete([]byte("band"))
+	d.Increment([]byte("banana"), 2)
+	want := map[string]int{"alexander": 1, "alexis": 5, "banana": 3}
+	check(d, want)
+	score := d.Load().TopKDecayed([]byte("alexis"), 1, time.Now())[0].Score
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The writes are replayed from the log, with their original times.
+	d = open()
+	check(d, want)
+	if got := d.Load().TopKDecayed([]byte("alexis"), 1, time.Now())[0].Score; got > score {
+		t.Fatalf("got score %v after replay, want at most %v", got, score)
+	}
+
+	// A record torn by a crash is dropped, and the log carries on after the
+	// last complete record.
+	d.Insert([]byte("cherry"), "cherry!")
+	d.Close()
+	b, err := os.ReadFile(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".wal", b[:len(b)-3], 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d = open()
+	check(d, want)
+	d.Insert([]byte("date"), "date!")
+	want["date"] = 1
+	d.Close()
+	d = open()
+	check(d, want)
+
+	// Compacting folds the log into the snapshot.
+	stale, err := os.ReadFile(path + ".wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path + ".wal"); err != nil || fi.Size() != int64(logHeader) {
+		t.Fatalf("got log %v after compaction: %v", fi, err)
+	}
+	d.Increment([]byte("date"), 1)
+	want["date"] = 2
+	d.Close()
+	d = open()
+	check(d, want)
+	d.Close()
+
+	// A crash between saving the snapshot and replacing the log leaves a
+	// log that was already folded, which must not be replayed again.
+	if err := os.WriteFile(path+".wal", stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	d = open()
+	want["date"] = 1
+	check(d, want)
+	d.Close()
+}
+
+func TestDurableCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.snapshot")
+	d, err := OpenDurable(path, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Increment([]byte("apple"), MaxCount); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Increment([]byte("apple"), MaxCount+1); !errors.Is(err, ErrCount) {
+		t.Fatalf("got %v, want %v", err, ErrCount)
+	}
+	if _, err := d.Decrement([]byte("apple"), MaxCount+1); !errors.Is(err, ErrCount) {
+		t.Fatalf("got %v, want %v", err, ErrCount)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The log only holds the counts that it replays.
+	d, err = OpenDurable(path, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, n, _ := d.Load().Get([]byte("apple")); n != MaxCount {
+		t.Fatalf("got count %d, want %d", n, MaxCount)
+	}
+}
+
+func TestDurableCompactFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dict.snapshot")
+	d, err := OpenDurable(path, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	d.Increment([]byte("apple"), 1)
+
+	// A directory in place of the log makes the new log fail to replace
+	// it, after the new snapshot is saved.
+	wal := path + ".wal"
+	if err := os.Remove(wal); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(wal, "x"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Compact(); err == nil {
+		t.Fatal("compacted over a directory")
+	}
+	// The old log no longer matches the snapshot, so the writes must fail
+	// rather than be discarded on the next open.
+	if err := d.Increment([]byte("apple"), 1); err == nil {
+		t.Fatal("wrote to the log of the old snapshot")
+	}
+
+	if err := os.RemoveAll(wal); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Increment([]byte("apple"), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	d, err = OpenDurable(path, New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	if _, n, _ := d.Load().Get([]byte("apple")); n != 2 {
+		t.Fatalf("got count %d, want 2", n)
+	}
+}